@@ -0,0 +1,69 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/storage/combine"
+)
+
+var CombineCommand = &cli.Command{
+	Name:     "combine",
+	HelpName: "combine",
+	Usage:    "manage combine buckets that map subdirectories to different upstreams",
+	Subcommands: []*cli.Command{
+		combineAddCommand,
+	},
+}
+
+var combineAddCommand = &cli.Command{
+	Name:      "add",
+	HelpName:  "combine add",
+	Usage:     "register a combine bucket, mapping directories to upstream s3 URLs",
+	ArgsUsage: "name dir=s3://bucket/prefix [dir2=s3://bucket2/prefix2 ...]",
+	Action: func(c *cli.Context) error {
+		if c.Args().Len() < 2 {
+			return fmt.Errorf("expected a combine bucket name and at least one dir=s3://... mapping")
+		}
+
+		name := c.Args().Get(0)
+
+		upstreams := make(map[string]string)
+		for _, arg := range c.Args().Slice()[1:] {
+			dir, upstream, err := parseCombineMapping(arg)
+			if err != nil {
+				return err
+			}
+			upstreams[dir] = upstream
+		}
+
+		cfg, err := combine.Load()
+		if err != nil {
+			return err
+		}
+
+		cfg.Buckets[name] = combine.Bucket{Upstreams: upstreams}
+
+		return combine.Save(cfg)
+	},
+}
+
+// parseCombineMapping parses a "dir=s3://bucket/prefix" argument.
+func parseCombineMapping(arg string) (dir string, upstream string, err error) {
+	parts := strings.SplitN(arg, "=", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("invalid mapping %q, expected dir=s3://bucket/prefix", arg)
+	}
+
+	dir, upstream = parts[0], parts[1]
+	if dir == "" || strings.Contains(dir, "/") {
+		return "", "", fmt.Errorf("invalid combine directory %q", dir)
+	}
+	if !strings.HasPrefix(upstream, "s3://") {
+		return "", "", fmt.Errorf("invalid upstream %q, expected an s3:// URL", upstream)
+	}
+
+	return dir, upstream, nil
+}
@@ -0,0 +1,36 @@
+package command
+
+import "testing"
+
+func TestParseCombineMapping(t *testing.T) {
+	tests := []struct {
+		arg        string
+		wantDir    string
+		wantUpRoot string
+		wantErr    bool
+	}{
+		{"logs=s3://bucket/prefix", "logs", "s3://bucket/prefix", false},
+		{"assets=s3://bucket/", "assets", "s3://bucket/", false},
+		{"missing-equals", "", "", true},
+		{"=s3://bucket", "", "", true},
+		{"logs/2024=s3://bucket", "", "", true},
+		{"logs=/local/path", "", "", true},
+	}
+
+	for _, tt := range tests {
+		dir, upstream, err := parseCombineMapping(tt.arg)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseCombineMapping(%q): expected error, got nil", tt.arg)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseCombineMapping(%q): unexpected error: %v", tt.arg, err)
+			continue
+		}
+		if dir != tt.wantDir || upstream != tt.wantUpRoot {
+			t.Errorf("parseCombineMapping(%q) = (%q, %q), want (%q, %q)", tt.arg, dir, upstream, tt.wantDir, tt.wantUpRoot)
+		}
+	}
+}
@@ -7,7 +7,9 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/urfave/cli/v2"
@@ -16,19 +18,148 @@ import (
 	"github.com/peak/s5cmd/log"
 	"github.com/peak/s5cmd/objurl"
 	"github.com/peak/s5cmd/parallel"
+	"github.com/peak/s5cmd/parallel/accounting"
 	"github.com/peak/s5cmd/storage"
 )
 
 // shouldOverrideFunc is a helper closure for shouldOverride function.
 type shouldOverrideFunc func(dst *objurl.ObjectURL) error
 
+// metadataOptions groups the --metadata/--preserve-metadata/
+// --if-metadata-differ/--exclude-metadata flags. doCopy uses all of them;
+// doUpload only merges userMetadata into the uploaded object's metadata.
+type metadataOptions struct {
+	userMetadata     map[string]string
+	preserveMetadata bool
+	ifMetadataDiffer bool
+	excludeMetadata  *regexp.Regexp
+}
+
+// monitoredMetadataHeaders are the headers compared for --if-metadata-differ
+// and copied for --preserve-metadata, in addition to any x-amz-meta-* user
+// metadata.
+var monitoredMetadataHeaders = []string{
+	"ContentType",
+	"CacheControl",
+	"ContentEncoding",
+	"ContentDisposition",
+}
+
+// parseMetadata parses a "key=value,key2=value2" flag value into a map.
+// Keys aren't required to be pre-prefixed: anything other than one of
+// monitoredMetadataHeaders or "StorageClass" is treated as custom user
+// metadata and mapped through metadataKey, since that's the only shape
+// storage.Client.Copy/Put route into a PutObject/CopyObject's Metadata.
+func parseMetadata(s string) (map[string]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid --metadata entry %q, expected key=value", pair)
+		}
+		metadata[metadataKey(kv[0])] = kv[1]
+	}
+	return metadata, nil
+}
+
+// metadataKey maps a user-supplied --metadata key to the key the storage
+// layer understands: one of the reserved header names (or "StorageClass")
+// verbatim, an already x-amz-meta-*-prefixed key verbatim, or anything
+// else prefixed with x-amz-meta- as custom user metadata.
+func metadataKey(k string) string {
+	if k == "StorageClass" || strings.HasPrefix(k, "x-amz-meta-") {
+		return k
+	}
+	for _, header := range monitoredMetadataHeaders {
+		if k == header {
+			return k
+		}
+	}
+	return "x-amz-meta-" + k
+}
+
+// baseCopyMetadata builds the metadata map doCopy passes to
+// storage.Client.Copy before any --preserve-metadata headers are merged
+// in. storageClass is only included when set: an empty entry would still
+// make the map non-empty, tripping S3.Copy's "metadata present" check into
+// sending MetadataDirective=REPLACE with StorageClass="" on every plain
+// copy, stripping the source's real headers instead of leaving S3's
+// default COPY behavior (preserve them) alone.
+func baseCopyMetadata(storageClass storage.StorageClass, userMetadata map[string]string) map[string]string {
+	metadata := make(map[string]string, len(userMetadata)+1)
+	if storageClass != "" {
+		metadata["StorageClass"] = string(storageClass)
+	}
+	for k, v := range userMetadata {
+		metadata[k] = v
+	}
+	return metadata
+}
+
+// compileExcludeMetadata compiles the --exclude-metadata regex flag, if
+// given.
+func compileExcludeMetadata(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile(pattern)
+}
+
+// filterMetadata returns a copy of metadata with any key matching exclude
+// removed.
+func filterMetadata(metadata map[string]string, exclude *regexp.Regexp) map[string]string {
+	if exclude == nil {
+		return metadata
+	}
+
+	filtered := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		if !exclude.MatchString(k) {
+			filtered[k] = v
+		}
+	}
+	return filtered
+}
+
+// metadataDiffers reports whether any monitored header or x-amz-meta-*
+// entry differs between src and dst, after excludeMetadata is applied.
+func metadataDiffers(src, dst map[string]string, exclude *regexp.Regexp) bool {
+	src = filterMetadata(src, exclude)
+	dst = filterMetadata(dst, exclude)
+
+	if len(src) != len(dst) {
+		return true
+	}
+	for k, v := range src {
+		if dst[k] != v {
+			return true
+		}
+	}
+	return false
+}
+
 var copyCommandFlags = []cli.Flag{
 	&cli.BoolFlag{Name: "no-clobber", Aliases: []string{"n"}},
 	&cli.BoolFlag{Name: "if-size-differ", Aliases: []string{"s"}},
 	&cli.BoolFlag{Name: "if-source-newer", Aliases: []string{"u"}},
+	&cli.BoolFlag{Name: "checksum"},
 	&cli.BoolFlag{Name: "parents"},
 	&cli.BoolFlag{Name: "recursive", Aliases: []string{"R"}},
 	&cli.StringFlag{Name: "storage-class"},
+	&cli.StringFlag{Name: "bwlimit", Usage: "limit bandwidth, e.g. 10M, 1M:off, or a schedule like '08:00,512k 12:00,10M'"},
+	&cli.DurationFlag{Name: "stats", Usage: "periodically print transfer statistics at the given interval"},
+	&cli.Int64Flag{Name: "upload-chunk-size", Value: storage.DefaultUploadChunkSize, Usage: "size (in bytes) of each part transferred in a multipart upload"},
+	&cli.IntFlag{Name: "upload-concurrency", Value: storage.DefaultUploadConcurrency, Usage: "number of parts to transfer concurrently in a multipart upload"},
+	&cli.Int64Flag{Name: "download-chunk-size", Value: storage.DefaultDownloadChunkSize, Usage: "size (in bytes) of each range downloaded concurrently"},
+	&cli.IntFlag{Name: "download-concurrency", Value: storage.DefaultDownloadConcurrency, Usage: "number of ranges to download concurrently"},
+	&cli.StringFlag{Name: "metadata", Usage: `set user metadata, e.g. "key=value,key2=value2"`},
+	&cli.BoolFlag{Name: "preserve-metadata", Usage: "copy headers (Content-Type, Cache-Control, x-amz-meta-*, ...) from the source object on remote-to-remote copies"},
+	&cli.BoolFlag{Name: "if-metadata-differ", Usage: "override the destination if any monitored metadata header differs from the source"},
+	&cli.StringFlag{Name: "exclude-metadata", Usage: "regex of metadata header names to ignore when comparing metadata"},
 }
 
 var CopyCommand = &cli.Command{
@@ -56,10 +187,46 @@ var CopyCommand = &cli.Command{
 		noClobber := c.Bool("no-clobber")
 		ifSizeDiffer := c.Bool("if-size-differ")
 		ifSourceNewer := c.Bool("if-source-newer")
+		checksum := c.Bool("checksum")
 		recursive := c.Bool("recursive")
 		parents := c.Bool("parents")
 		storageClass := storage.LookupClass(c.String("storage-class"))
 
+		limiter, err := accounting.NewTokenBucket(c.String("bwlimit"))
+		if err != nil {
+			return err
+		}
+
+		if interval := c.Duration("stats"); interval > 0 {
+			go printStats(c.Context, interval)
+		}
+
+		putOpts := storage.PutOptions{
+			ChunkSize:   c.Int64("upload-chunk-size"),
+			Concurrency: c.Int("upload-concurrency"),
+		}
+		getOpts := storage.GetOptions{
+			ChunkSize:   c.Int64("download-chunk-size"),
+			Concurrency: c.Int("download-concurrency"),
+		}
+
+		userMetadata, err := parseMetadata(c.String("metadata"))
+		if err != nil {
+			return err
+		}
+
+		excludeMetadata, err := compileExcludeMetadata(c.String("exclude-metadata"))
+		if err != nil {
+			return err
+		}
+
+		metaOpts := metadataOptions{
+			userMetadata:     userMetadata,
+			preserveMetadata: c.Bool("preserve-metadata"),
+			ifMetadataDiffer: c.Bool("if-metadata-differ"),
+			excludeMetadata:  excludeMetadata,
+		}
+
 		return Copy(
 			c.Context,
 			c.Args().Get(0),
@@ -71,9 +238,14 @@ var CopyCommand = &cli.Command{
 			noClobber,
 			ifSizeDiffer,
 			ifSourceNewer,
+			checksum,
 			recursive,
 			parents,
 			storageClass,
+			limiter,
+			putOpts,
+			getOpts,
+			metaOpts,
 		)
 	},
 }
@@ -119,9 +291,14 @@ func Copy(
 	noClobber bool,
 	ifSizeDiffer bool,
 	ifSourceNewer bool,
+	checksum bool,
 	recursive bool,
 	parents bool,
 	storageClass storage.StorageClass,
+	limiter *accounting.TokenBucket,
+	putOpts storage.PutOptions,
+	getOpts storage.GetOptions,
+	metaOpts metadataOptions,
 ) error {
 	srcurl, err := objurl.New(src)
 	if err != nil {
@@ -144,6 +321,15 @@ func Copy(
 
 	waiter := parallel.NewWaiter()
 
+	// Once ctx is cancelled (SIGINT, --timeout), stop scheduling new
+	// per-object Tasks; the ones already running keep going so their
+	// in-flight S3 requests can unwind (and, for multipart uploads,
+	// self-abort) instead of being torn down mid-transfer.
+	go func() {
+		<-ctx.Done()
+		waiter.Stop()
+	}()
+
 	var merror error
 	go func() {
 		for err := range waiter.Err() {
@@ -192,8 +378,10 @@ func Copy(
 					deleteSource,
 					shouldOverrideFunc,
 					// flags
+					checksum,
 					parents,
 					storageClass,
+					metaOpts,
 				)
 				if err != nil {
 					return &errorpkg.Error{
@@ -220,7 +408,10 @@ func Copy(
 					deleteSource,
 					shouldOverrideFunc,
 					// flags
+					checksum,
 					parents,
+					limiter,
+					getOpts,
 				)
 
 				if err != nil {
@@ -243,8 +434,12 @@ func Copy(
 					deleteSource,
 					shouldOverrideFunc,
 					// flags
+					checksum,
 					parents,
 					storageClass,
+					limiter,
+					putOpts,
+					metaOpts,
 				)
 				if err != nil {
 					return &errorpkg.Error{
@@ -277,7 +472,10 @@ func doDownload(
 	deleteSource bool,
 	shouldOverride shouldOverrideFunc,
 	// flags
+	checksum bool,
 	parents bool,
+	limiter *accounting.TokenBucket,
+	getOpts storage.GetOptions,
 ) error {
 	srcClient, err := storage.NewClient(src)
 	if err != nil {
@@ -289,6 +487,17 @@ func doDownload(
 		return err
 	}
 
+	if checksum {
+		equal, hashType, err := checkHashes(ctx, srcClient, src, dstClient, dst)
+		if err != nil {
+			return err
+		}
+		if equal {
+			printDebug(op, src, dst, fmt.Errorf("skipped: hashes match (%s)", hashType))
+			return nil
+		}
+	}
+
 	err = shouldOverride(dst)
 	if err != nil {
 		// FIXME(ig): rename
@@ -305,7 +514,11 @@ func doDownload(
 	}
 	defer f.Close()
 
-	size, err := srcClient.Get(ctx, src, f)
+	transfer := accounting.NewTransfer(dst.String(), 0, limiter)
+	defer transfer.Done()
+	getOpts.Progress = transfer.Add
+
+	size, err := srcClient.Get(ctx, src, f, getOpts)
 	if err != nil {
 		err = dstClient.Delete(ctx, dst)
 	} else if deleteSource {
@@ -337,8 +550,12 @@ func doUpload(
 	deleteSource bool,
 	shouldOverride shouldOverrideFunc,
 	// flags
+	checksum bool,
 	parents bool,
 	storageClass storage.StorageClass,
+	limiter *accounting.TokenBucket,
+	putOpts storage.PutOptions,
+	metaOpts metadataOptions,
 ) error {
 	// TODO(ig): use storage abstraction
 	f, err := os.Open(src.Absolute())
@@ -354,17 +571,33 @@ func doUpload(
 
 	dst = dst.Join(objname)
 
-	err = shouldOverride(dst)
+	dstClient, err := storage.NewClient(dst)
 	if err != nil {
-		if isWarning(err) {
-			printDebug(op, src, dst, err)
+		return err
+	}
+
+	if checksum {
+		srcClient, err := storage.NewClient(src)
+		if err != nil {
+			return err
+		}
+
+		equal, hashType, err := checkHashes(ctx, srcClient, src, dstClient, dst)
+		if err != nil {
+			return err
+		}
+		if equal {
+			printDebug(op, src, dst, fmt.Errorf("skipped: hashes match (%s)", hashType))
 			return nil
 		}
-		return err
 	}
 
-	dstClient, err := storage.NewClient(dst)
+	err = shouldOverride(dst)
 	if err != nil {
+		if isWarning(err) {
+			printDebug(op, src, dst, err)
+			return nil
+		}
 		return err
 	}
 
@@ -372,12 +605,20 @@ func doUpload(
 		"StorageClass": string(storageClass),
 		"ContentType":  guessContentType(f),
 	}
+	for k, v := range metaOpts.userMetadata {
+		metadata[k] = v
+	}
+
+	transfer := accounting.NewTransfer(dst.String(), 0, limiter)
+	defer transfer.Done()
+	putOpts.Progress = transfer.Add
 
 	err = dstClient.Put(
 		ctx,
 		f,
 		dst,
 		metadata,
+		putOpts,
 	)
 	if err != nil {
 		return err
@@ -419,25 +660,77 @@ func doCopy(
 	deleteSource bool,
 	shouldOverride shouldOverrideFunc,
 	// flags
+	checksum bool,
 	parents bool,
 	storageClass storage.StorageClass,
+	metaOpts metadataOptions,
 ) error {
 	srcClient, err := storage.NewClient(src)
 	if err != nil {
 		return err
 	}
 
-	metadata := map[string]string{
-		"StorageClass": string(storageClass),
+	dstClient, err := storage.NewClient(dst)
+	if err != nil {
+		return err
+	}
+
+	metadata := baseCopyMetadata(storageClass, metaOpts.userMetadata)
+
+	var srcObj *storage.Object
+	if metaOpts.preserveMetadata || metaOpts.ifMetadataDiffer {
+		srcObj, err = srcClient.Stat(ctx, src)
+		if err != nil {
+			return err
+		}
+	}
+
+	if metaOpts.preserveMetadata {
+		for _, header := range monitoredMetadataHeaders {
+			if v, ok := srcObj.Metadata[header]; ok {
+				if _, alreadySet := metadata[header]; !alreadySet {
+					metadata[header] = v
+				}
+			}
+		}
+		for k, v := range srcObj.Metadata {
+			if strings.HasPrefix(k, "x-amz-meta-") {
+				if _, alreadySet := metadata[k]; !alreadySet {
+					metadata[k] = v
+				}
+			}
+		}
+	}
+
+	forceOverride := false
+	if metaOpts.ifMetadataDiffer {
+		if dstObj, err := dstClient.Stat(ctx, dst); err == nil {
+			forceOverride = metadataDiffers(srcObj.Metadata, dstObj.Metadata, metaOpts.excludeMetadata)
+		}
+	}
+
+	if checksum && !forceOverride {
+		equal, hashType, err := checkHashes(ctx, srcClient, src, dstClient, dst)
+		if err != nil {
+			return err
+		}
+		if equal {
+			printDebug(op, src, dst, fmt.Errorf("skipped: hashes match (%s)", hashType))
+			return nil
+		}
 	}
 
 	err = shouldOverride(dst)
 	if err != nil {
 		if isWarning(err) {
-			printDebug(op, src, dst, err)
-			return nil
+			if !forceOverride {
+				printDebug(op, src, dst, err)
+				return nil
+			}
+			printDebug(op, src, dst, fmt.Errorf("overriding: metadata differs"))
+		} else {
+			return err
 		}
-		return err
 	}
 
 	err = srcClient.Copy(
@@ -470,6 +763,54 @@ func doCopy(
 	return nil
 }
 
+// checkHashes compares the content hashes of src and dst, picking a hash
+// type that both storage.Client implementations support. It returns whether
+// the hashes are equal, the hash type that was used for the comparison, and
+// any error encountered while computing them. If src and dst share no
+// common hash type, equal is false and hashType is empty.
+func checkHashes(
+	ctx context.Context,
+	srcClient storage.Client,
+	src *objurl.ObjectURL,
+	dstClient storage.Client,
+	dst *objurl.ObjectURL,
+) (equal bool, hashType string, err error) {
+	hashType, ok := commonHashType(srcClient.Hashes(), dstClient.Hashes())
+	if !ok {
+		return false, "", nil
+	}
+
+	srcHash, err := srcClient.Hash(ctx, src, hashType)
+	if err != nil {
+		return false, hashType, err
+	}
+
+	dstHash, err := dstClient.Hash(ctx, dst, hashType)
+	if err != nil {
+		if err == storage.ErrGivenObjectNotFound {
+			return false, hashType, nil
+		}
+		return false, hashType, err
+	}
+
+	return srcHash == dstHash, hashType, nil
+}
+
+// commonHashType returns the first hash type present in both src and dst,
+// preferring earlier entries in src's list.
+func commonHashType(src, dst []string) (string, bool) {
+	want := make(map[string]bool, len(dst))
+	for _, t := range dst {
+		want[t] = true
+	}
+	for _, t := range src {
+		if want[t] {
+			return t, true
+		}
+	}
+	return "", false
+}
+
 func guessContentType(rs io.ReadSeeker) string {
 	defer rs.Seek(0, io.SeekStart)
 
@@ -482,6 +823,30 @@ func guessContentType(rs io.ReadSeeker) string {
 	return http.DetectContentType(buf)
 }
 
+// printStats periodically prints an aggregated transfer stats snapshot to
+// stderr until ctx is done. It is started as a goroutine when --stats is
+// given a positive interval.
+func printStats(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			snap := accounting.GlobalStats().Snapshot()
+			fmt.Fprintf(
+				os.Stderr,
+				"Transferred: %d bytes, %.2f B/s, in-flight: %d\n",
+				snap.TotalBytes,
+				snap.AverageSpeed,
+				snap.InFlight,
+			)
+		}
+	}
+}
+
 func givenCommand(c *cli.Context) string {
 	return fmt.Sprintf("%v %v", c.Command.FullName(), strings.Join(c.Args().Slice(), " "))
 }
@@ -0,0 +1,160 @@
+package command
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/peak/s5cmd/objurl"
+	"github.com/peak/s5cmd/storage"
+)
+
+func TestParseMetadata(t *testing.T) {
+	got, err := parseMetadata("reviewer=bob,ContentType=text/plain,x-amz-meta-team=infra,StorageClass=STANDARD_IA")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"x-amz-meta-reviewer": "bob",
+		"ContentType":         "text/plain",
+		"x-amz-meta-team":     "infra",
+		"StorageClass":        "STANDARD_IA",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("parseMetadata() = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Fatalf("parseMetadata()[%q] = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestBaseCopyMetadataOmitsEmptyStorageClass(t *testing.T) {
+	// A plain `cp` with no --storage-class and no --metadata must produce
+	// an empty map, so S3.Copy's "metadata present" guard leaves
+	// MetadataDirective unset and S3's default COPY preserves the
+	// source's headers, instead of REPLACE-ing them with nothing.
+	got := baseCopyMetadata("", nil)
+	if len(got) != 0 {
+		t.Fatalf("baseCopyMetadata(\"\", nil) = %v, want empty map", got)
+	}
+
+	got = baseCopyMetadata("STANDARD_IA", map[string]string{"x-amz-meta-a": "1"})
+	want := map[string]string{"StorageClass": "STANDARD_IA", "x-amz-meta-a": "1"}
+	if len(got) != len(want) || got["StorageClass"] != want["StorageClass"] || got["x-amz-meta-a"] != want["x-amz-meta-a"] {
+		t.Fatalf("baseCopyMetadata(...) = %v, want %v", got, want)
+	}
+}
+
+func TestCommonHashType(t *testing.T) {
+	tests := []struct {
+		name     string
+		src, dst []string
+		want     string
+		wantOK   bool
+	}{
+		{"no overlap", []string{"md5", "sha1"}, []string{"etag"}, "", false},
+		{"single shared", []string{"md5", "etag"}, []string{"etag"}, "etag", true},
+		{"prefers src order", []string{"md5", "etag"}, []string{"etag", "md5"}, "md5", true},
+		{"empty src", nil, []string{"etag"}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := commonHashType(tt.src, tt.dst)
+			if ok != tt.wantOK || got != tt.want {
+				t.Fatalf("commonHashType(%v, %v) = (%q, %v), want (%q, %v)", tt.src, tt.dst, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+// hashOnlyClient is a minimal storage.Client stub that only supports
+// Hashes/Hash, for exercising checkHashes without a real backend.
+type hashOnlyClient struct {
+	hashes  []string
+	hash    string
+	hashErr error
+}
+
+func (c *hashOnlyClient) List(context.Context, *objurl.ObjectURL, bool, storage.ListMask) <-chan *storage.Object {
+	panic("not implemented")
+}
+func (c *hashOnlyClient) Stat(context.Context, *objurl.ObjectURL) (*storage.Object, error) {
+	panic("not implemented")
+}
+func (c *hashOnlyClient) Copy(context.Context, *objurl.ObjectURL, *objurl.ObjectURL, map[string]string) error {
+	panic("not implemented")
+}
+func (c *hashOnlyClient) Get(context.Context, *objurl.ObjectURL, io.WriterAt, storage.GetOptions) (int64, error) {
+	panic("not implemented")
+}
+func (c *hashOnlyClient) Put(context.Context, io.Reader, *objurl.ObjectURL, map[string]string, storage.PutOptions) error {
+	panic("not implemented")
+}
+func (c *hashOnlyClient) Delete(context.Context, *objurl.ObjectURL) error {
+	panic("not implemented")
+}
+func (c *hashOnlyClient) Hashes() []string { return c.hashes }
+func (c *hashOnlyClient) Hash(ctx context.Context, url *objurl.ObjectURL, hashType string) (string, error) {
+	return c.hash, c.hashErr
+}
+
+func TestCheckHashes(t *testing.T) {
+	ctx := context.Background()
+	src, _ := objurl.New("s3://bucket/a")
+	dst, _ := objurl.New("s3://bucket/b")
+
+	t.Run("no common hash type skips comparison", func(t *testing.T) {
+		srcClient := &hashOnlyClient{hashes: []string{"md5"}, hash: "x"}
+		dstClient := &hashOnlyClient{hashes: []string{"etag"}, hash: "x"}
+
+		equal, hashType, err := checkHashes(ctx, srcClient, src, dstClient, dst)
+		if err != nil || equal || hashType != "" {
+			t.Fatalf("got (%v, %q, %v), want (false, \"\", nil)", equal, hashType, err)
+		}
+	})
+
+	t.Run("matching hashes are equal", func(t *testing.T) {
+		srcClient := &hashOnlyClient{hashes: []string{"etag"}, hash: "same"}
+		dstClient := &hashOnlyClient{hashes: []string{"etag"}, hash: "same"}
+
+		equal, hashType, err := checkHashes(ctx, srcClient, src, dstClient, dst)
+		if err != nil || !equal || hashType != "etag" {
+			t.Fatalf("got (%v, %q, %v), want (true, \"etag\", nil)", equal, hashType, err)
+		}
+	})
+
+	t.Run("dst not found is treated as not equal", func(t *testing.T) {
+		srcClient := &hashOnlyClient{hashes: []string{"etag"}, hash: "same"}
+		dstClient := &hashOnlyClient{hashes: []string{"etag"}, hashErr: storage.ErrGivenObjectNotFound}
+
+		equal, _, err := checkHashes(ctx, srcClient, src, dstClient, dst)
+		if err != nil || equal {
+			t.Fatalf("got (%v, %v), want (false, nil)", equal, err)
+		}
+	})
+}
+
+func TestMetadataDiffers(t *testing.T) {
+	src := map[string]string{"ContentType": "text/plain", "x-amz-meta-a": "1"}
+
+	if metadataDiffers(src, src, nil) {
+		t.Fatal("identical metadata reported as differing")
+	}
+
+	dst := map[string]string{"ContentType": "application/json", "x-amz-meta-a": "1"}
+	if !metadataDiffers(src, dst, nil) {
+		t.Fatal("differing ContentType not detected")
+	}
+
+	excluded, err := compileExcludeMetadata("^ContentType$")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadataDiffers(src, dst, excluded) {
+		t.Fatal("excluded header still triggered a diff")
+	}
+}
@@ -0,0 +1,105 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/peak/s5cmd/log"
+	"github.com/peak/s5cmd/objurl"
+	"github.com/peak/s5cmd/storage"
+)
+
+// warningError marks an error that should be reported as a skip rather
+// than a failure: the operation didn't happen, but that's the expected
+// outcome given the flags in effect (e.g. --no-clobber on an existing
+// object), not something gone wrong.
+type warningError struct {
+	err error
+}
+
+func (w *warningError) Error() string { return w.err.Error() }
+func (w *warningError) Unwrap() error { return w.err }
+
+func warning(format string, args ...interface{}) error {
+	return &warningError{err: fmt.Errorf(format, args...)}
+}
+
+// isWarning reports whether err (or anything it wraps) is a warningError.
+func isWarning(err error) bool {
+	var w *warningError
+	return errors.As(err, &w)
+}
+
+// shouldOverride checks whether dst may be written to, given the
+// --no-clobber/--if-size-differ/--if-source-newer flags. A non-nil
+// warningError means the destination should be left alone; any other
+// error is a real failure.
+func shouldOverride(
+	ctx context.Context,
+	src *objurl.ObjectURL,
+	dst *objurl.ObjectURL,
+	noClobber bool,
+	ifSizeDiffer bool,
+	ifSourceNewer bool,
+) error {
+	if !noClobber && !ifSizeDiffer && !ifSourceNewer {
+		return nil
+	}
+
+	dstClient, err := storage.NewClient(dst)
+	if err != nil {
+		return err
+	}
+
+	dstObj, err := dstClient.Stat(ctx, dst)
+	if err == storage.ErrGivenObjectNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if noClobber {
+		return warning("object already exists")
+	}
+
+	srcClient, err := storage.NewClient(src)
+	if err != nil {
+		return err
+	}
+
+	srcObj, err := srcClient.Stat(ctx, src)
+	if err != nil {
+		return err
+	}
+
+	if ifSizeDiffer && srcObj.Size == dstObj.Size {
+		return warning("object sizes match")
+	}
+
+	if ifSourceNewer && !srcObj.ModTime.After(dstObj.ModTime) {
+		return warning("source is not newer")
+	}
+
+	return nil
+}
+
+// printError reports a failed operation to stderr.
+func printError(fullCommand, op string, err error) {
+	log.Error(log.ErrorMessage{
+		Command: fullCommand,
+		Op:      op,
+		Err:     err,
+	})
+}
+
+// printDebug reports a skipped or informational operation to stderr.
+func printDebug(op string, src, dst *objurl.ObjectURL, err error) {
+	log.Debug(log.DebugMessage{
+		Operation:   op,
+		Source:      src,
+		Destination: dst,
+		Err:         err,
+	})
+}
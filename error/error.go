@@ -0,0 +1,30 @@
+// Package error defines the error types s5cmd commands wrap storage
+// failures in so that callers can report which operation, source and
+// destination were involved.
+package error
+
+import (
+	"fmt"
+
+	"github.com/peak/s5cmd/objurl"
+)
+
+// Error wraps an underlying error with the operation and URLs it occurred
+// for, so that top-level error printing can report useful context.
+type Error struct {
+	Op  string
+	Src *objurl.ObjectURL
+	Dst *objurl.ObjectURL
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Dst != nil {
+		return fmt.Sprintf("%q %s -> %q: %v", e.Src, e.Op, e.Dst, e.Err)
+	}
+	return fmt.Sprintf("%q %s: %v", e.Src, e.Op, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
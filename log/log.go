@@ -0,0 +1,67 @@
+// Package log prints the structured operation messages s5cmd commands emit
+// for every object they touch.
+package log
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/peak/s5cmd/objurl"
+	"github.com/peak/s5cmd/storage"
+)
+
+// InfoMessage describes a completed operation on a single object, printed
+// once the operation has succeeded.
+type InfoMessage struct {
+	Operation   string
+	Source      *objurl.ObjectURL
+	Destination *objurl.ObjectURL
+	Object      *storage.Object
+}
+
+func (m InfoMessage) String() string {
+	if m.Destination != nil {
+		return fmt.Sprintf("%s %s %s", m.Operation, m.Source, m.Destination)
+	}
+	return fmt.Sprintf("%s %s", m.Operation, m.Source)
+}
+
+// Info prints a successful-operation message to stdout.
+func Info(msg InfoMessage) {
+	fmt.Fprintln(os.Stdout, msg)
+}
+
+// ErrorMessage describes a failed operation, printed to stderr.
+type ErrorMessage struct {
+	Command string
+	Op      string
+	Err     error
+}
+
+func (m ErrorMessage) String() string {
+	return fmt.Sprintf("ERROR %q %s: %v", m.Command, m.Op, m.Err)
+}
+
+// Error prints a failed-operation message to stderr.
+func Error(msg ErrorMessage) {
+	fmt.Fprintln(os.Stderr, msg)
+}
+
+// DebugMessage describes a skipped or informational operation, printed to
+// stderr. It is kept as its own message type so how it is printed can
+// change without touching call sites.
+type DebugMessage struct {
+	Operation   string
+	Source      *objurl.ObjectURL
+	Destination *objurl.ObjectURL
+	Err         error
+}
+
+func (m DebugMessage) String() string {
+	return fmt.Sprintf("DEBUG %q %s -> %q: %v", m.Source, m.Operation, m.Destination, m.Err)
+}
+
+// Debug prints a debug message to stderr.
+func Debug(msg DebugMessage) {
+	fmt.Fprintln(os.Stderr, msg)
+}
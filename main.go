@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/command"
+)
+
+// abortGracePeriod is how long a second SIGINT/SIGTERM waits before force
+// exiting, giving in-flight S3 multipart uploads a chance to notice the
+// already-cancelled context and issue their own AbortMultipartUpload
+// before the process dies out from under them.
+const abortGracePeriod = 3 * time.Second
+
+// timeoutCancel releases the context.WithTimeout set up in app.Before, if
+// any. It's a no-op otherwise.
+var timeoutCancel context.CancelFunc = func() {}
+
+var app = &cli.App{
+	Name:  "s5cmd",
+	Usage: "Blazing fast S3 and local filesystem execution tool",
+	Flags: []cli.Flag{
+		&cli.DurationFlag{Name: "timeout", Usage: "cancel operations after the given duration, e.g. 10m, 1h"},
+	},
+	Before: func(c *cli.Context) error {
+		if d := c.Duration("timeout"); d > 0 {
+			ctx, cancel := context.WithTimeout(c.Context, d)
+			c.Context = ctx
+			timeoutCancel = cancel
+		}
+		return nil
+	},
+	Commands: []*cli.Command{
+		command.CopyCommand,
+		command.CombineCommand,
+	},
+}
+
+func main() {
+	ctx, cancel := newRootContext()
+	defer cancel()
+	defer timeoutCancel()
+
+	if err := app.RunContext(ctx, os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// newRootContext returns a context that is cancelled on the first
+// SIGINT/SIGTERM. Cancellation lets in-flight work drain on its own: tasks
+// see ctx.Done(), return, and parallel.Waiter.Stop() (wired in by the
+// command layer) stops scheduling new ones, while any in-flight S3
+// multipart upload has its context cancelled out from under it and, per
+// s3manager, issues its own AbortMultipartUpload as part of unwinding.
+//
+// A second SIGINT/SIGTERM means the user wants out immediately. Rather
+// than os.Exit on the spot -- which would kill the process before that
+// AbortMultipartUpload request has a chance to leave the machine -- it
+// waits out abortGracePeriod first.
+//
+// --timeout is handled separately, in app.Before, once the cli.Context
+// (and its flag values) exist; it composes with this cancellation rather
+// than replacing it.
+func newRootContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		<-sigs
+		cancel()
+
+		<-sigs
+		time.Sleep(abortGracePeriod)
+		os.Exit(130)
+	}()
+
+	return ctx, cancel
+}
@@ -0,0 +1,143 @@
+// Package objurl parses and represents the source/destination arguments
+// s5cmd commands operate on: local filesystem paths, s3:// URLs, and
+// combine:// URLs that resolve to a registered combine bucket (see the
+// storage/combine package).
+package objurl
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Type distinguishes the kind of storage a URL refers to.
+type Type int
+
+const (
+	// Local represents a path on the local filesystem.
+	Local Type = iota
+	// Remote represents an s3:// URL.
+	Remote
+	// Combine represents a combine:// URL, resolved against a registered
+	// combine bucket at storage-client construction time.
+	Combine
+)
+
+const (
+	remoteScheme  = "s3://"
+	combineScheme = "combine://"
+)
+
+// globCharacters are the characters that mark a path segment as a glob
+// pattern rather than a literal path.
+const globCharacters = "*?["
+
+// ObjectURL represents a parsed source or destination argument.
+type ObjectURL struct {
+	Type   Type
+	Bucket string // set for Remote and Combine URLs
+	Path   string
+}
+
+// New parses s into an ObjectURL. s3:// URLs are parsed as Remote,
+// combine:// URLs as Combine, and everything else is treated as a Local
+// path.
+func New(s string) (*ObjectURL, error) {
+	switch {
+	case strings.HasPrefix(s, remoteScheme):
+		return parseBucketURL(s, remoteScheme, Remote)
+	case strings.HasPrefix(s, combineScheme):
+		return parseBucketURL(s, combineScheme, Combine)
+	default:
+		return &ObjectURL{Type: Local, Path: filepath.ToSlash(s)}, nil
+	}
+}
+
+func parseBucketURL(s, scheme string, typ Type) (*ObjectURL, error) {
+	rest := strings.TrimPrefix(s, scheme)
+	if rest == "" {
+		return nil, fmt.Errorf("invalid URL %q: missing bucket name", s)
+	}
+
+	parts := strings.SplitN(rest, "/", 2)
+	bucket := parts[0]
+	if bucket == "" {
+		return nil, fmt.Errorf("invalid URL %q: missing bucket name", s)
+	}
+
+	var p string
+	if len(parts) == 2 {
+		p = parts[1]
+	}
+
+	return &ObjectURL{Type: typ, Bucket: bucket, Path: p}, nil
+}
+
+// IsRemote reports whether the URL refers to anything other than the local
+// filesystem, i.e. it must be resolved through a storage.Client backend.
+func (u *ObjectURL) IsRemote() bool {
+	return u.Type != Local
+}
+
+// HasGlob reports whether Path contains any glob characters.
+func (u *ObjectURL) HasGlob() bool {
+	return strings.ContainsAny(u.Path, globCharacters)
+}
+
+// Absolute returns the path to use for local filesystem operations.
+func (u *ObjectURL) Absolute() string {
+	if u.Type != Local {
+		return u.Path
+	}
+	abs, err := filepath.Abs(filepath.FromSlash(u.Path))
+	if err != nil {
+		return u.Path
+	}
+	return abs
+}
+
+// Base returns the last path segment.
+func (u *ObjectURL) Base() string {
+	return path.Base(u.Path)
+}
+
+// Relative returns Path as given, with any leading slash trimmed, for use
+// when --parents is set and the full relative layout must be preserved.
+func (u *ObjectURL) Relative() string {
+	return strings.TrimPrefix(u.Path, "/")
+}
+
+// Dir returns the URL for the parent directory of Path, as a string.
+func (u *ObjectURL) Dir() string {
+	clone := u.clone()
+	clone.Path = path.Dir(strings.TrimSuffix(u.Path, "/"))
+	if clone.Path == "." {
+		clone.Path = ""
+	}
+	return clone.String()
+}
+
+// Join returns a new ObjectURL with name appended to Path.
+func (u *ObjectURL) Join(name string) *ObjectURL {
+	clone := u.clone()
+	clone.Path = path.Join(clone.Path, name)
+	return clone
+}
+
+func (u *ObjectURL) clone() *ObjectURL {
+	c := *u
+	return &c
+}
+
+// String returns the canonical URL representation.
+func (u *ObjectURL) String() string {
+	switch u.Type {
+	case Remote:
+		return remoteScheme + u.Bucket + "/" + u.Path
+	case Combine:
+		return combineScheme + u.Bucket + "/" + u.Path
+	default:
+		return u.Path
+	}
+}
@@ -0,0 +1,160 @@
+// Package accounting tracks the progress, throughput, and bandwidth usage of
+// in-flight transfers. It is patterned after rclone's fs/accounting package:
+// every upload/download creates a Transfer and feeds it bytes moved via
+// Add as the storage layer reports them, which updates a process-wide
+// stats registry and optionally blocks on a shared TokenBucket to enforce
+// --bwlimit.
+package accounting
+
+import (
+	"sync"
+	"time"
+)
+
+// globalStats is the process-wide registry of in-flight and completed
+// transfers. It backs the --stats reporter.
+var globalStats = &Stats{}
+
+// Stats is a snapshot-queryable aggregate of transfer activity.
+type Stats struct {
+	mu        sync.Mutex
+	inFlight  map[*Transfer]struct{}
+	totalSize int64
+	started   time.Time
+}
+
+// Snapshot is a point-in-time view of Stats, safe to read without holding
+// any lock.
+type Snapshot struct {
+	InFlight     int
+	TotalBytes   int64
+	AverageSpeed float64 // bytes/sec since the first transfer started
+}
+
+// GlobalStats returns the process-wide Stats registry.
+func GlobalStats() *Stats {
+	return globalStats
+}
+
+func (s *Stats) add(t *Transfer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inFlight == nil {
+		s.inFlight = make(map[*Transfer]struct{})
+	}
+	if s.started.IsZero() {
+		s.started = time.Now()
+	}
+	s.inFlight[t] = struct{}{}
+}
+
+func (s *Stats) remove(t *Transfer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.inFlight, t)
+	s.totalSize += t.bytes()
+}
+
+// Snapshot returns the current aggregate stats.
+func (s *Stats) Snapshot() Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	total := s.totalSize
+	for t := range s.inFlight {
+		total += t.bytes()
+	}
+
+	elapsed := time.Since(s.started).Seconds()
+	var avg float64
+	if elapsed > 0 {
+		avg = float64(total) / elapsed
+	}
+
+	return Snapshot{
+		InFlight:     len(s.inFlight),
+		TotalBytes:   total,
+		AverageSpeed: avg,
+	}
+}
+
+// Transfer tracks bytes moved, elapsed time and ETA for a single object
+// transfer. Create one with NewTransfer and feed it progress via Add as
+// the storage.Client backing the transfer reports bytes written.
+type Transfer struct {
+	name    string
+	size    int64
+	start   time.Time
+	limiter *TokenBucket
+
+	mu    sync.Mutex
+	moved int64
+}
+
+// NewTransfer registers a new Transfer of the given total size (use 0 if
+// unknown) with the global stats registry.
+func NewTransfer(name string, size int64, limiter *TokenBucket) *Transfer {
+	t := &Transfer{
+		name:    name,
+		size:    size,
+		start:   time.Now(),
+		limiter: limiter,
+	}
+	globalStats.add(t)
+	return t
+}
+
+// Done removes the transfer from the in-flight set and folds its byte count
+// into the cumulative total.
+func (t *Transfer) Done() {
+	globalStats.remove(t)
+}
+
+func (t *Transfer) bytes() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.moved
+}
+
+func (t *Transfer) account(n int) {
+	t.mu.Lock()
+	t.moved += int64(n)
+	t.mu.Unlock()
+	if t.limiter != nil {
+		t.limiter.Wait(n)
+	}
+}
+
+// Add records n bytes moved and blocks on the Transfer's TokenBucket, if
+// any. It is passed as storage.PutOptions.Progress/GetOptions.Progress so
+// the storage layer can report bytes as they cross the wire, including
+// during concurrent multipart uploads/downloads where there is no single
+// io.Reader/io.Writer to wrap.
+func (t *Transfer) Add(n int) {
+	t.account(n)
+}
+
+// Rate returns the average bytes/sec moved so far.
+func (t *Transfer) Rate() float64 {
+	elapsed := time.Since(t.start).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(t.bytes()) / elapsed
+}
+
+// ETA returns the estimated time remaining, or 0 if the size is unknown or
+// already reached.
+func (t *Transfer) ETA() time.Duration {
+	moved := t.bytes()
+	if t.size <= 0 || moved >= t.size {
+		return 0
+	}
+	rate := t.Rate()
+	if rate <= 0 {
+		return 0
+	}
+	remaining := float64(t.size - moved)
+	return time.Duration(remaining/rate) * time.Second
+}
+
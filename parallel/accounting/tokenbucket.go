@@ -0,0 +1,183 @@
+package accounting
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenBucket rate-limits byte consumption across every wrapped reader and
+// writer in the process. A nil *TokenBucket (or one configured "off")
+// imposes no limit.
+//
+// It is implemented as virtual scheduling rather than a literal bucket of
+// tokens: next tracks the point in time at which the bucket will next have
+// capacity. Wait only holds mu long enough to advance next by this call's
+// cost and read back how long it must personally sleep; the sleep itself
+// happens unlocked, so concurrent callers queue up fairly against the
+// shared rate instead of serializing through one goroutine's time.Sleep.
+type TokenBucket struct {
+	rate int64 // bytes/sec, 0 means unlimited
+
+	schedule []scheduledRate // sorted by time-of-day, empty if not scheduled
+
+	mu   sync.Mutex
+	next time.Time // zero until the first Wait call
+}
+
+type scheduledRate struct {
+	atMinute int // minutes since midnight
+	rate     int64
+}
+
+// NewTokenBucket parses a --bwlimit value and returns a TokenBucket that
+// enforces it. Accepted forms:
+//
+//	10M           a flat limit of 10 MiB/s
+//	1M:off        (reserved for future burst tuning; the suffix is ignored)
+//	08:00,512k 12:00,10M
+//	              a schedule: 512KiB/s starting at 08:00, 10MiB/s from 12:00
+//
+// A value of "off" or "" disables limiting.
+func NewTokenBucket(spec string) (*TokenBucket, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" || spec == "off" {
+		return &TokenBucket{}, nil
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) == 1 && !strings.Contains(fields[0], ",") {
+		rate, err := parseRate(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		return &TokenBucket{rate: rate}, nil
+	}
+
+	schedule := make([]scheduledRate, 0, len(fields))
+	for _, f := range fields {
+		parts := strings.SplitN(f, ",", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid --bwlimit schedule entry %q", f)
+		}
+
+		minute, err := parseTimeOfDay(parts[0])
+		if err != nil {
+			return nil, err
+		}
+
+		rate, err := parseRate(parts[1])
+		if err != nil {
+			return nil, err
+		}
+
+		schedule = append(schedule, scheduledRate{atMinute: minute, rate: rate})
+	}
+	sort.Slice(schedule, func(i, j int) bool {
+		return schedule[i].atMinute < schedule[j].atMinute
+	})
+
+	return &TokenBucket{schedule: schedule}, nil
+}
+
+func parseTimeOfDay(s string) (int, error) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid time-of-day %q, expected HH:MM", s)
+	}
+	h, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, fmt.Errorf("invalid time-of-day %q: %v", s, err)
+	}
+	m, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, fmt.Errorf("invalid time-of-day %q: %v", s, err)
+	}
+	return h*60 + m, nil
+}
+
+// parseRate parses a value like "10M", "512k", "1M:off" into bytes/sec.
+// "off" (with or without a trailing ":off" qualifier) means unlimited.
+func parseRate(s string) (int64, error) {
+	s = strings.SplitN(s, ":", 2)[0]
+	if s == "off" {
+		return 0, nil
+	}
+
+	if s == "" {
+		return 0, fmt.Errorf("empty rate")
+	}
+
+	mult := int64(1)
+	switch s[len(s)-1] {
+	case 'k', 'K':
+		mult = 1 << 10
+		s = s[:len(s)-1]
+	case 'm', 'M':
+		mult = 1 << 20
+		s = s[:len(s)-1]
+	case 'g', 'G':
+		mult = 1 << 30
+		s = s[:len(s)-1]
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate %q: %v", s, err)
+	}
+	return n * mult, nil
+}
+
+// currentRate returns the active bytes/sec limit, resolving the schedule
+// (if any) against the current time of day. 0 means unlimited.
+func (b *TokenBucket) currentRate() int64 {
+	if len(b.schedule) == 0 {
+		return b.rate
+	}
+
+	now := time.Now()
+	minute := now.Hour()*60 + now.Minute()
+
+	rate := b.schedule[len(b.schedule)-1].rate
+	for _, s := range b.schedule {
+		if minute < s.atMinute {
+			break
+		}
+		rate = s.rate
+	}
+	return rate
+}
+
+// Wait blocks until n bytes may be consumed under the current rate limit.
+// It is safe to call on a nil *TokenBucket.
+func (b *TokenBucket) Wait(n int) {
+	if b == nil {
+		return
+	}
+
+	rate := b.currentRate()
+	if rate <= 0 {
+		return
+	}
+
+	cost := time.Duration(float64(n) / float64(rate) * float64(time.Second))
+
+	b.mu.Lock()
+	now := time.Now()
+	if b.next.Before(now) {
+		b.next = now
+	}
+	start := b.next
+	b.next = b.next.Add(cost)
+	b.mu.Unlock()
+
+	// Sleeping outside the lock is what lets other transfers keep
+	// accounting for their own bytes concurrently instead of queuing up
+	// behind this call's sleep.
+	if d := start.Sub(now); d > 0 {
+		time.Sleep(d)
+	}
+}
@@ -0,0 +1,73 @@
+package accounting
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewTokenBucket(t *testing.T) {
+	tests := []struct {
+		spec     string
+		wantRate int64
+		wantErr  bool
+	}{
+		{"", 0, false},
+		{"off", 0, false},
+		{"10M", 10 << 20, false},
+		{"512k", 512 << 10, false},
+		{"1M:off", 1 << 20, false},
+		{"not-a-rate", 0, true},
+	}
+
+	for _, tt := range tests {
+		b, err := NewTokenBucket(tt.spec)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("NewTokenBucket(%q): expected error, got nil", tt.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("NewTokenBucket(%q): unexpected error: %v", tt.spec, err)
+		}
+		if b.currentRate() != tt.wantRate {
+			t.Errorf("NewTokenBucket(%q).currentRate() = %d, want %d", tt.spec, b.currentRate(), tt.wantRate)
+		}
+	}
+}
+
+// TestTokenBucketWaitDoesNotSerialize ensures Wait does not hold a lock
+// across its sleep: concurrent callers must each be able to start their
+// own wait window rather than queuing up behind one goroutine's
+// time.Sleep. With the bug, each caller waits out every earlier caller's
+// sleep on top of its own, so the total grows much faster than N; fixed,
+// the total time reflects the shared rate limit applied once across all
+// bytes, n*perCall/rate.
+func TestTokenBucketWaitDoesNotSerialize(t *testing.T) {
+	const rate = 1 << 20 // 1MiB/s
+	const perCall = 64 * 1024
+	const n = 8
+
+	b := &TokenBucket{rate: rate}
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.Wait(perCall)
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	// n concurrent calls against a shared bucket should take roughly
+	// n*perCall/rate (all bytes drawn from the same rate limit), not the
+	// much longer total that holding a lock across Sleep produces.
+	want := time.Duration(float64(n*perCall) / float64(rate) * float64(time.Second))
+	if elapsed > want*2 {
+		t.Fatalf("Wait calls appear serialized: took %v, expected around %v", elapsed, want)
+	}
+}
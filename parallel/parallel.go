@@ -0,0 +1,106 @@
+// Package parallel bounds the number of concurrently running commands
+// (e.g. one per object in a `cp --recursive`) behind a fixed-size worker
+// pool, and collects their errors.
+package parallel
+
+import (
+	"runtime"
+	"sync"
+)
+
+// Task is a unit of work submitted to Run.
+type Task func() error
+
+// numWorkers bounds how many Tasks run concurrently across the process.
+// It defaults to runtime.NumCPU.
+var numWorkers = runtime.NumCPU()
+
+var (
+	poolOnce sync.Once
+	pool     chan struct{}
+)
+
+func workerPool() chan struct{} {
+	poolOnce.Do(func() {
+		pool = make(chan struct{}, numWorkers)
+	})
+	return pool
+}
+
+// Waiter tracks a batch of Tasks submitted via Run: it collects every
+// error on a channel and blocks until all submitted Tasks have returned.
+type Waiter struct {
+	wg  sync.WaitGroup
+	err chan error
+
+	mu       sync.Mutex
+	draining bool
+}
+
+// NewWaiter returns a Waiter ready to track a new batch of Tasks.
+func NewWaiter() *Waiter {
+	return &Waiter{err: make(chan error)}
+}
+
+// Err returns the channel Run sends failed Task errors on. It is closed
+// once Wait returns.
+func (w *Waiter) Err() <-chan error {
+	return w.err
+}
+
+// Wait blocks until every Task submitted to Run with this Waiter has
+// completed, then closes the error channel.
+func (w *Waiter) Wait() {
+	w.wg.Wait()
+	close(w.err)
+}
+
+// Stop marks the Waiter as draining: Tasks already scheduled against it
+// are still run to completion (so in-flight uploads/downloads can clean
+// up after themselves), but Run silently drops any further Task without
+// scheduling it. It is used to implement graceful cancellation: once the
+// root context is cancelled, callers stop feeding new Tasks in, and Stop
+// ensures any that are queued up concurrently are discarded rather than
+// piling up behind the ones still draining.
+func (w *Waiter) Stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.draining = true
+}
+
+func (w *Waiter) isDraining() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.draining
+}
+
+// Run schedules fn to run on the shared worker pool, reporting any error
+// it returns on waiter.Err(). If waiter has been stopped via Stop, fn is
+// dropped without being scheduled. Run blocks the caller until a worker
+// slot is available, which is how it bounds concurrency across a
+// recursive operation; a slot is held until its task returns.
+func Run(fn Task, waiter *Waiter) {
+	if waiter.isDraining() {
+		return
+	}
+
+	waiter.wg.Add(1)
+	workers := workerPool()
+	workers <- struct{}{}
+
+	// Stop may have been called while this call waited for a slot.
+	if waiter.isDraining() {
+		<-workers
+		waiter.wg.Done()
+		return
+	}
+
+	go func() {
+		defer waiter.wg.Done()
+		defer func() { <-workers }()
+
+		if err := fn(); err != nil {
+			waiter.err <- err
+		}
+	}()
+}
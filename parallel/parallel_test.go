@@ -0,0 +1,66 @@
+package parallel
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestRunCollectsErrors(t *testing.T) {
+	waiter := NewWaiter()
+
+	Run(func() error { return nil }, waiter)
+	Run(func() error { return errBoom }, waiter)
+
+	var errs []error
+	done := make(chan struct{})
+	go func() {
+		for err := range waiter.Err() {
+			errs = append(errs, err)
+		}
+		close(done)
+	}()
+
+	waiter.Wait()
+	<-done
+
+	if len(errs) != 1 || errs[0] != errBoom {
+		t.Fatalf("errs = %v, want [%v]", errs, errBoom)
+	}
+}
+
+func TestWaiterStopDrainsInFlightButDropsNew(t *testing.T) {
+	waiter := NewWaiter()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var ran int32
+
+	Run(func() error {
+		atomic.AddInt32(&ran, 1)
+		close(started)
+		<-release
+		return nil
+	}, waiter)
+
+	<-started
+	waiter.Stop()
+
+	// Submitted after Stop: must be dropped, not scheduled.
+	Run(func() error {
+		atomic.AddInt32(&ran, 2)
+		return nil
+	}, waiter)
+
+	close(release)
+	waiter.Wait()
+
+	if got := atomic.LoadInt32(&ran); got != 1 {
+		t.Fatalf("ran = %d, want 1 (only the in-flight task should have executed)", got)
+	}
+}
+
+var errBoom = &testError{"boom"}
+
+type testError struct{ msg string }
+
+func (e *testError) Error() string { return e.msg }
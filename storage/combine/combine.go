@@ -0,0 +1,312 @@
+package combine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/peak/s5cmd/objurl"
+	"github.com/peak/s5cmd/storage"
+)
+
+func init() {
+	storage.RegisterCombineFactory(NewClient)
+}
+
+// upstream pairs a registered directory's parsed URL with the
+// storage.Client that serves it.
+type upstream struct {
+	url    *objurl.ObjectURL
+	client storage.Client
+}
+
+// Client is the storage.Client implementation for combine:// URLs: it
+// resolves the first path segment of whatever URL it's given against the
+// bucket's registered directories, then delegates to that directory's
+// upstream client for everything else.
+type Client struct {
+	bucket    string
+	upstreams map[string]upstream
+}
+
+// NewClient loads the persisted combine configuration and returns a
+// Client for url.Bucket, the combine bucket name. It is registered with
+// storage.NewClient via storage.RegisterCombineFactory in this package's
+// init func.
+func NewClient(url *objurl.ObjectURL) (storage.Client, error) {
+	cfg, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	bucket, ok := cfg.Buckets[url.Bucket]
+	if !ok {
+		return nil, fmt.Errorf("no combine bucket registered with name %q", url.Bucket)
+	}
+
+	upstreams := make(map[string]upstream, len(bucket.Upstreams))
+	for dir, rawURL := range bucket.Upstreams {
+		upURL, err := objurl.New(rawURL)
+		if err != nil {
+			return nil, fmt.Errorf("combine bucket %q: %v", url.Bucket, err)
+		}
+
+		upClient, err := storage.NewClient(upURL)
+		if err != nil {
+			return nil, fmt.Errorf("combine bucket %q: %v", url.Bucket, err)
+		}
+
+		upstreams[dir] = upstream{url: upURL, client: upClient}
+	}
+
+	return &Client{bucket: url.Bucket, upstreams: upstreams}, nil
+}
+
+// splitFirstSegment splits p into its first path segment and the
+// remainder. ok is false if p has no segment (the combine bucket root).
+func splitFirstSegment(p string) (first, rest string, ok bool) {
+	p = strings.TrimPrefix(p, "/")
+	if p == "" {
+		return "", "", false
+	}
+	if i := strings.Index(p, "/"); i >= 0 {
+		return p[:i], p[i+1:], true
+	}
+	return p, "", true
+}
+
+func (c *Client) resolve(p string) (dir string, up upstream, upstreamURL *objurl.ObjectURL, err error) {
+	dir, rest, ok := splitFirstSegment(p)
+	if !ok {
+		return "", upstream{}, nil, fmt.Errorf("combine bucket %q: path %q does not name a registered directory", c.bucket, p)
+	}
+
+	up, ok = c.upstreams[dir]
+	if !ok {
+		return "", upstream{}, nil, fmt.Errorf("combine bucket %q: no upstream registered for directory %q", c.bucket, dir)
+	}
+
+	return dir, up, up.url.Join(rest), nil
+}
+
+// remap turns an object URL from dir's upstream backend back into a
+// combine:// URL rooted at dir. It reports ok=false if objURL.Path isn't
+// actually under up.url.Path at a "/"-terminated segment boundary, which
+// happens when S3's substring-based ListObjectsV2 prefix matching returns
+// an unrelated sibling key (a directory registered at prefix "logs" also
+// matches a key under "logs2/"); such objects don't belong to dir at all
+// and must not be remapped into it.
+func (c *Client) remap(dir string, up upstream, objURL *objurl.ObjectURL) (*objurl.ObjectURL, bool) {
+	rel, ok := trimPrefixSegment(objURL.Path, up.url.Path)
+	if !ok {
+		return nil, false
+	}
+
+	return &objurl.ObjectURL{
+		Type:   objurl.Combine,
+		Bucket: c.bucket,
+		Path:   path.Join(dir, rel),
+	}, true
+}
+
+// trimPrefixSegment trims prefix from p, but only at a path segment
+// boundary: p must equal prefix exactly, or start with prefix+"/". A bare
+// strings.TrimPrefix would also match "logs2/x" against prefix "logs",
+// producing the nonsensical relative path "2/x".
+func trimPrefixSegment(p, prefix string) (rel string, ok bool) {
+	if prefix == "" {
+		return strings.TrimPrefix(p, "/"), true
+	}
+	if p == prefix {
+		return "", true
+	}
+	if strings.HasPrefix(p, prefix+"/") {
+		return p[len(prefix)+1:], true
+	}
+	return "", false
+}
+
+func (c *Client) sortedDirs() []string {
+	dirs := make([]string, 0, len(c.upstreams))
+	for dir := range c.upstreams {
+		dirs = append(dirs, dir)
+	}
+	for i := 1; i < len(dirs); i++ {
+		for j := i; j > 0 && dirs[j-1] > dirs[j]; j-- {
+			dirs[j-1], dirs[j] = dirs[j], dirs[j-1]
+		}
+	}
+	return dirs
+}
+
+func (c *Client) List(ctx context.Context, url *objurl.ObjectURL, isRecursive bool, mask storage.ListMask) <-chan *storage.Object {
+	ch := make(chan *storage.Object)
+
+	go func() {
+		defer close(ch)
+
+		dir, rest, ok := splitFirstSegment(url.Path)
+		if !ok {
+			// Listing the combine bucket root: one virtual directory per
+			// registered upstream, descending into each when recursive.
+			for _, dir := range c.sortedDirs() {
+				dirURL := &objurl.ObjectURL{Type: objurl.Combine, Bucket: c.bucket, Path: dir}
+				ch <- &storage.Object{URL: dirURL, Type: storage.ObjectTypeDir}
+				if isRecursive {
+					c.listDir(ctx, dir, "", true, ch)
+				}
+			}
+			return
+		}
+
+		c.listDir(ctx, dir, rest, isRecursive, ch)
+	}()
+
+	return ch
+}
+
+func (c *Client) listDir(ctx context.Context, dir, rest string, isRecursive bool, ch chan<- *storage.Object) {
+	up, ok := c.upstreams[dir]
+	if !ok {
+		ch <- &storage.Object{Err: fmt.Errorf("combine bucket %q: no upstream registered for directory %q", c.bucket, dir)}
+		return
+	}
+
+	for obj := range up.client.List(ctx, up.url.Join(rest), isRecursive, storage.ListAllItems) {
+		if obj.Err == nil {
+			remapped, ok := c.remap(dir, up, obj.URL)
+			if !ok {
+				// Not actually under dir's upstream prefix; see remap's
+				// doc comment. Drop it instead of misattributing it.
+				continue
+			}
+			obj.URL = remapped
+		}
+		ch <- obj
+	}
+}
+
+func (c *Client) Stat(ctx context.Context, url *objurl.ObjectURL) (*storage.Object, error) {
+	dir, up, upstreamURL, err := c.resolve(url.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := up.client.Stat(ctx, upstreamURL)
+	if err != nil {
+		return nil, err
+	}
+
+	remapped, ok := c.remap(dir, up, obj.URL)
+	if !ok {
+		return nil, fmt.Errorf("combine bucket %q: upstream returned %q outside of directory %q", c.bucket, obj.URL.Path, dir)
+	}
+	obj.URL = remapped
+	return obj, nil
+}
+
+func (c *Client) Copy(ctx context.Context, src, dst *objurl.ObjectURL, metadata map[string]string) error {
+	_, srcUp, srcURL, err := c.resolve(src.Path)
+	if err != nil {
+		return err
+	}
+
+	_, dstUp, dstURL, err := c.resolve(dst.Path)
+	if err != nil {
+		return err
+	}
+
+	if srcUp.url.Bucket == dstUp.url.Bucket {
+		return srcUp.client.Copy(ctx, srcURL, dstURL, metadata)
+	}
+
+	// The two directories resolve to different upstream buckets: a
+	// server-side CopyObject isn't guaranteed to be possible across them
+	// (different accounts/credentials), so fall back to streaming the
+	// object through this process.
+	return c.copyAcrossUpstreams(ctx, srcUp, srcURL, dstUp, dstURL, metadata)
+}
+
+func (c *Client) copyAcrossUpstreams(
+	ctx context.Context,
+	srcUp upstream, srcURL *objurl.ObjectURL,
+	dstUp upstream, dstURL *objurl.ObjectURL,
+	metadata map[string]string,
+) error {
+	pr, pw := io.Pipe()
+
+	errCh := make(chan error, 1)
+	go func() {
+		// Concurrency: 1 keeps parts flowing to writerAtAdapter strictly
+		// in order, since it only supports sequential offsets.
+		_, err := srcUp.client.Get(ctx, srcURL, &writerAtAdapter{w: pw}, storage.GetOptions{Concurrency: 1})
+		errCh <- err
+		pw.CloseWithError(err)
+	}()
+
+	putErr := dstUp.client.Put(ctx, pr, dstURL, metadata, storage.PutOptions{})
+	getErr := <-errCh
+	if putErr != nil {
+		return putErr
+	}
+	return getErr
+}
+
+// writerAtAdapter lets a plain io.Writer (here, one end of an io.Pipe) be
+// used where storage.Client.Get wants an io.WriterAt. It only supports
+// sequential, in-order writes starting at offset 0, which is exactly
+// what Get does with Concurrency: 1, as copyAcrossUpstreams passes above.
+type writerAtAdapter struct {
+	w      io.Writer
+	offset int64
+}
+
+func (a *writerAtAdapter) WriteAt(p []byte, off int64) (int, error) {
+	if off != a.offset {
+		return 0, fmt.Errorf("combine: out-of-order write at offset %d, expected %d", off, a.offset)
+	}
+	n, err := a.w.Write(p)
+	a.offset += int64(n)
+	return n, err
+}
+
+func (c *Client) Get(ctx context.Context, url *objurl.ObjectURL, w io.WriterAt, opts storage.GetOptions) (int64, error) {
+	_, up, upstreamURL, err := c.resolve(url.Path)
+	if err != nil {
+		return 0, err
+	}
+	return up.client.Get(ctx, upstreamURL, w, opts)
+}
+
+func (c *Client) Put(ctx context.Context, r io.Reader, url *objurl.ObjectURL, metadata map[string]string, opts storage.PutOptions) error {
+	_, up, upstreamURL, err := c.resolve(url.Path)
+	if err != nil {
+		return err
+	}
+	return up.client.Put(ctx, r, upstreamURL, metadata, opts)
+}
+
+func (c *Client) Delete(ctx context.Context, url *objurl.ObjectURL) error {
+	_, up, upstreamURL, err := c.resolve(url.Path)
+	if err != nil {
+		return err
+	}
+	return up.client.Delete(ctx, upstreamURL)
+}
+
+// Hashes reports "etag" unconditionally: combine buckets only ever
+// register s3:// upstreams (see parseCombineMapping), and every S3
+// backend supports ETag hashing.
+func (c *Client) Hashes() []string {
+	return []string{"etag"}
+}
+
+func (c *Client) Hash(ctx context.Context, url *objurl.ObjectURL, hashType string) (string, error) {
+	_, up, upstreamURL, err := c.resolve(url.Path)
+	if err != nil {
+		return "", err
+	}
+	return up.client.Hash(ctx, upstreamURL, hashType)
+}
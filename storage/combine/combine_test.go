@@ -0,0 +1,240 @@
+package combine
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/peak/s5cmd/objurl"
+	"github.com/peak/s5cmd/storage"
+)
+
+func TestSplitFirstSegment(t *testing.T) {
+	tests := []struct {
+		path      string
+		wantFirst string
+		wantRest  string
+		wantOK    bool
+	}{
+		{"", "", "", false},
+		{"/", "", "", false},
+		{"logs", "logs", "", true},
+		{"logs/2024/01.log", "logs", "2024/01.log", true},
+		{"/logs/2024/01.log", "logs", "2024/01.log", true},
+	}
+
+	for _, tt := range tests {
+		first, rest, ok := splitFirstSegment(tt.path)
+		if first != tt.wantFirst || rest != tt.wantRest || ok != tt.wantOK {
+			t.Errorf("splitFirstSegment(%q) = (%q, %q, %v), want (%q, %q, %v)",
+				tt.path, first, rest, ok, tt.wantFirst, tt.wantRest, tt.wantOK)
+		}
+	}
+}
+
+func TestTrimPrefixSegment(t *testing.T) {
+	tests := []struct {
+		path    string
+		prefix  string
+		wantRel string
+		wantOK  bool
+	}{
+		{"logs", "logs", "", true},
+		{"logs/2024/01.log", "logs", "2024/01.log", true},
+		{"anything", "", "anything", true},
+		// A sibling key that merely shares a byte prefix with "logs" (as
+		// S3's substring-based ListObjectsV2 prefix matching would return
+		// alongside the real matches) must not be treated as living under it.
+		{"logs2/01.log", "logs", "", false},
+		{"logs-archive", "logs", "", false},
+	}
+
+	for _, tt := range tests {
+		rel, ok := trimPrefixSegment(tt.path, tt.prefix)
+		if rel != tt.wantRel || ok != tt.wantOK {
+			t.Errorf("trimPrefixSegment(%q, %q) = (%q, %v), want (%q, %v)",
+				tt.path, tt.prefix, rel, ok, tt.wantRel, tt.wantOK)
+		}
+	}
+}
+
+// stubClient is a minimal in-memory storage.Client for exercising
+// Client's resolution/delegation logic without a network-backed S3 or
+// Filesystem client.
+type stubClient struct {
+	bucket  string
+	objects map[string][]byte
+}
+
+// List returns every object whose key has url.Path as a byte prefix,
+// mirroring S3 ListObjectsV2's substring-based (not segment-aware) prefix
+// matching -- including keys under an unrelated sibling "directory" that
+// merely happens to share that prefix.
+func (s *stubClient) List(ctx context.Context, url *objurl.ObjectURL, isRecursive bool, mask storage.ListMask) <-chan *storage.Object {
+	ch := make(chan *storage.Object)
+
+	go func() {
+		defer close(ch)
+
+		var keys []string
+		for k := range s.objects {
+			if strings.HasPrefix(k, url.Path) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			objURL := *url
+			objURL.Path = k
+			ch <- &storage.Object{URL: &objURL, Size: int64(len(s.objects[k]))}
+		}
+	}()
+
+	return ch
+}
+func (s *stubClient) Stat(ctx context.Context, url *objurl.ObjectURL) (*storage.Object, error) {
+	data, ok := s.objects[url.Path]
+	if !ok {
+		return nil, storage.ErrGivenObjectNotFound
+	}
+	return &storage.Object{URL: url, Size: int64(len(data))}, nil
+}
+func (s *stubClient) Copy(ctx context.Context, src, dst *objurl.ObjectURL, metadata map[string]string) error {
+	data, ok := s.objects[src.Path]
+	if !ok {
+		return storage.ErrGivenObjectNotFound
+	}
+	s.objects[dst.Path] = data
+	return nil
+}
+func (s *stubClient) Get(ctx context.Context, url *objurl.ObjectURL, w io.WriterAt, opts storage.GetOptions) (int64, error) {
+	data, ok := s.objects[url.Path]
+	if !ok {
+		return 0, storage.ErrGivenObjectNotFound
+	}
+	n, err := w.WriteAt(data, 0)
+	return int64(n), err
+}
+func (s *stubClient) Put(ctx context.Context, r io.Reader, url *objurl.ObjectURL, metadata map[string]string, opts storage.PutOptions) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.objects[url.Path] = data
+	return nil
+}
+func (s *stubClient) Delete(ctx context.Context, url *objurl.ObjectURL) error {
+	delete(s.objects, url.Path)
+	return nil
+}
+func (s *stubClient) Hashes() []string { return []string{"etag"} }
+func (s *stubClient) Hash(ctx context.Context, url *objurl.ObjectURL, hashType string) (string, error) {
+	return "", nil
+}
+
+func newTestClient() (*Client, *stubClient, *stubClient) {
+	logsUp := &stubClient{bucket: "logs-bucket", objects: map[string][]byte{"prefix/a.log": []byte("hello")}}
+	assetsUp := &stubClient{bucket: "assets-bucket", objects: map[string][]byte{}}
+
+	logsURL, _ := objurl.New("s3://logs-bucket/prefix")
+	assetsURL, _ := objurl.New("s3://assets-bucket/")
+
+	c := &Client{
+		bucket: "mybucket",
+		upstreams: map[string]upstream{
+			"logs":   {url: logsURL, client: logsUp},
+			"assets": {url: assetsURL, client: assetsUp},
+		},
+	}
+	return c, logsUp, assetsUp
+}
+
+func TestClientGetDelegatesToUpstream(t *testing.T) {
+	c, _, _ := newTestClient()
+
+	url, _ := objurl.New("combine://mybucket/logs/a.log")
+	var buf bytes.Buffer
+	n, err := c.Get(context.Background(), url, &singleWriterAt{buf: &buf}, storage.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 5 || buf.String() != "hello" {
+		t.Fatalf("got (%d, %q), want (5, \"hello\")", n, buf.String())
+	}
+}
+
+func TestClientGetUnknownDirectory(t *testing.T) {
+	c, _, _ := newTestClient()
+
+	url, _ := objurl.New("combine://mybucket/unknown/a.log")
+	_, err := c.Get(context.Background(), url, &singleWriterAt{buf: &bytes.Buffer{}}, storage.GetOptions{})
+	if err == nil {
+		t.Fatal("expected an error for an unregistered directory")
+	}
+}
+
+func TestClientListDropsSiblingPrefixMatches(t *testing.T) {
+	c, logsUp, _ := newTestClient()
+	// Shares the "prefix" byte prefix with the registered "logs" upstream
+	// (s3://logs-bucket/prefix) but lives under an unrelated "prefix2/"
+	// key -- exactly what S3 ListObjectsV2 would return alongside the
+	// real matches.
+	logsUp.objects["prefix2/sibling.log"] = []byte("nope")
+
+	url, _ := objurl.New("combine://mybucket/logs")
+	var got []string
+	for obj := range c.List(context.Background(), url, true, storage.ListAllItems) {
+		if obj.Err != nil {
+			t.Fatalf("unexpected error: %v", obj.Err)
+		}
+		got = append(got, obj.URL.Path)
+	}
+
+	want := []string{"logs/a.log"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("List() = %v, want %v (sibling key under prefix2/ must not leak into logs/)", got, want)
+	}
+}
+
+func TestClientCopySameUpstreamBucket(t *testing.T) {
+	c, logsUp, _ := newTestClient()
+
+	src, _ := objurl.New("combine://mybucket/logs/a.log")
+	dst, _ := objurl.New("combine://mybucket/logs/b.log")
+	if err := c.Copy(context.Background(), src, dst, nil); err != nil {
+		t.Fatal(err)
+	}
+	if string(logsUp.objects["prefix/b.log"]) != "hello" {
+		t.Fatal("same-upstream copy did not land in the upstream's store")
+	}
+}
+
+func TestClientCopyAcrossUpstreams(t *testing.T) {
+	c, _, assetsUp := newTestClient()
+
+	src, _ := objurl.New("combine://mybucket/logs/a.log")
+	dst, _ := objurl.New("combine://mybucket/assets/a.log")
+	if err := c.Copy(context.Background(), src, dst, nil); err != nil {
+		t.Fatal(err)
+	}
+	if string(assetsUp.objects["a.log"]) != "hello" {
+		t.Fatal("cross-upstream copy did not stream into the destination's store")
+	}
+}
+
+type singleWriterAt struct {
+	buf *bytes.Buffer
+}
+
+func (s *singleWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	if off != int64(s.buf.Len()) {
+		return 0, io.ErrShortWrite
+	}
+	return s.buf.Write(p)
+}
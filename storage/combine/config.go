@@ -0,0 +1,84 @@
+// Package combine implements the storage.Client backend for combine://
+// URLs: a combine bucket maps its top-level directory segments to
+// different upstream s3:// URLs, registered via `s5cmd combine add` and
+// persisted to ~/.s5cmd/combine.yaml.
+package combine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// configDir and configFile locate the persisted combine bucket
+// configuration, one directory mapping per combine bucket name.
+const configDir = ".s5cmd"
+const configFile = "combine.yaml"
+
+// Config is the on-disk representation of every combine bucket that has
+// been registered via `s5cmd combine add`, keyed by bucket name.
+type Config struct {
+	Buckets map[string]Bucket
+}
+
+// Bucket maps a combine bucket's directory segments to the upstream
+// s3:// URL each one should be resolved against.
+type Bucket struct {
+	Upstreams map[string]string
+}
+
+func configPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, configDir, configFile), nil
+}
+
+// Load reads the persisted combine bucket configuration, returning an
+// empty Config if none has been saved yet.
+func Load() (*Config, error) {
+	path, err := configPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Config{Buckets: make(map[string]Bucket)}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg.Buckets); err != nil {
+		return nil, err
+	}
+	if cfg.Buckets == nil {
+		cfg.Buckets = make(map[string]Bucket)
+	}
+
+	return &cfg, nil
+}
+
+// Save persists cfg to the combine bucket configuration file.
+func Save(cfg *Config) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(cfg.Buckets)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,238 @@
+package storage
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/peak/s5cmd/objurl"
+)
+
+// Filesystem is the Client implementation for objurl.Local URLs.
+type Filesystem struct{}
+
+// NewFilesystem returns a Filesystem client.
+func NewFilesystem() *Filesystem {
+	return &Filesystem{}
+}
+
+func (f *Filesystem) List(ctx context.Context, url *objurl.ObjectURL, isRecursive bool, mask ListMask) <-chan *Object {
+	ch := make(chan *Object)
+
+	go func() {
+		defer close(ch)
+
+		root := url.Absolute()
+		if url.HasGlob() {
+			matches, err := filepath.Glob(root)
+			if err != nil {
+				ch <- &Object{Err: err}
+				return
+			}
+			for _, m := range matches {
+				f.walk(ctx, m, isRecursive, ch)
+			}
+			return
+		}
+
+		f.walk(ctx, root, isRecursive, ch)
+	}()
+
+	return ch
+}
+
+func (f *Filesystem) walk(ctx context.Context, root string, isRecursive bool, ch chan<- *Object) {
+	entries, err := ioutil.ReadDir(root)
+	if err != nil {
+		ch <- &Object{Err: err}
+		return
+	}
+
+	for _, entry := range entries {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		full := filepath.Join(root, entry.Name())
+		typ := ObjectTypeFile
+		if entry.IsDir() {
+			typ = ObjectTypeDir
+		}
+
+		url, err := objurl.New(full)
+		if err != nil {
+			ch <- &Object{Err: err}
+			continue
+		}
+
+		if entry.IsDir() && isRecursive {
+			f.walk(ctx, full, isRecursive, ch)
+			continue
+		}
+
+		ch <- &Object{
+			URL:     url,
+			Type:    typ,
+			Size:    entry.Size(),
+			ModTime: entry.ModTime(),
+		}
+	}
+}
+
+func (f *Filesystem) Stat(ctx context.Context, url *objurl.ObjectURL) (*Object, error) {
+	fi, err := os.Stat(url.Absolute())
+	if os.IsNotExist(err) {
+		return nil, ErrGivenObjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	typ := ObjectTypeFile
+	if fi.IsDir() {
+		typ = ObjectTypeDir
+	}
+
+	return &Object{
+		URL:     url,
+		Type:    typ,
+		Size:    fi.Size(),
+		ModTime: fi.ModTime(),
+	}, nil
+}
+
+func (f *Filesystem) Copy(ctx context.Context, src, dst *objurl.ObjectURL, metadata map[string]string) error {
+	in, err := os.Open(src.Absolute())
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst.Absolute()), os.ModePerm); err != nil {
+		return err
+	}
+
+	out, err := os.Create(dst.Absolute())
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	return err
+}
+
+func (f *Filesystem) Get(ctx context.Context, url *objurl.ObjectURL, w io.WriterAt, opts GetOptions) (int64, error) {
+	in, err := os.Open(url.Absolute())
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	var written int64
+	buf := make([]byte, 32*1024)
+	for {
+		n, readErr := in.Read(buf)
+		if n > 0 {
+			if _, err := w.WriteAt(buf[:n], written); err != nil {
+				return written, err
+			}
+			written += int64(n)
+			if opts.Progress != nil {
+				opts.Progress(n)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return written, readErr
+		}
+	}
+	return written, nil
+}
+
+func (f *Filesystem) Put(ctx context.Context, r io.Reader, url *objurl.ObjectURL, metadata map[string]string, opts PutOptions) error {
+	if err := os.MkdirAll(filepath.Dir(url.Absolute()), os.ModePerm); err != nil {
+		return err
+	}
+
+	out, err := os.Create(url.Absolute())
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			if _, err := out.Write(buf[:n]); err != nil {
+				return err
+			}
+			if opts.Progress != nil {
+				opts.Progress(n)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+	return nil
+}
+
+func (f *Filesystem) Delete(ctx context.Context, url *objurl.ObjectURL) error {
+	return retryDelete(ctx, func() error {
+		return os.Remove(url.Absolute())
+	})
+}
+
+// Hashes returns the hash types Filesystem can compute on demand. "etag" is
+// included because, for objects uploaded to S3 as a single part, the S3
+// ETag is exactly the hex MD5 of the content; comparing against it lets
+// --checksum work across a local<->remote pair for non-multipart objects.
+// It is never a false match: if the remote object was a multipart upload,
+// its real ETag won't match and the comparison correctly falls through to
+// re-uploading instead of incorrectly skipping.
+func (f *Filesystem) Hashes() []string {
+	return []string{"md5", "sha1", "etag"}
+}
+
+func (f *Filesystem) Hash(ctx context.Context, url *objurl.ObjectURL, hashType string) (string, error) {
+	file, err := os.Open(url.Absolute())
+	if os.IsNotExist(err) {
+		return "", ErrGivenObjectNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	switch hashType {
+	case "md5", "etag":
+		h := md5.New()
+		if _, err := io.Copy(h, file); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	case "sha1":
+		h := sha1.New()
+		if _, err := io.Copy(h, file); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	default:
+		return "", fmt.Errorf("unsupported hash type %q", hashType)
+	}
+}
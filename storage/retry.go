@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// maxDeleteRetries bounds how many attempts Delete makes in total (the
+// first one included) before giving up and returning the last error.
+const maxDeleteRetries = 3
+
+// deleteRetryBackoff is the base delay between Delete retries; it doubles
+// after each attempt.
+const deleteRetryBackoff = 200 * time.Millisecond
+
+// retryDelete runs fn up to maxDeleteRetries times with exponential
+// backoff, stopping early and returning ctx.Err() the moment ctx is
+// cancelled -- whether that's before the first attempt, or while waiting
+// out the backoff between two of them.
+func retryDelete(ctx context.Context, fn func() error) error {
+	var err error
+	for attempt := 0; attempt < maxDeleteRetries; attempt++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err = fn(); err == nil {
+			return nil
+		}
+
+		if attempt == maxDeleteRetries-1 {
+			break
+		}
+
+		backoff := deleteRetryBackoff * time.Duration(1<<uint(attempt))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+	}
+	return err
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryDeleteSucceedsAfterTransientFailures(t *testing.T) {
+	attempts := 0
+	err := retryDelete(context.Background(), func() error {
+		attempts++
+		if attempts < 2 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if attempts != 2 {
+		t.Fatalf("attempts = %d, want 2", attempts)
+	}
+}
+
+func TestRetryDeleteGivesUpAfterMaxRetries(t *testing.T) {
+	attempts := 0
+	wantErr := errors.New("permanent")
+	err := retryDelete(context.Background(), func() error {
+		attempts++
+		return wantErr
+	})
+	if err != wantErr {
+		t.Fatalf("err = %v, want %v", err, wantErr)
+	}
+	if attempts != maxDeleteRetries {
+		t.Fatalf("attempts = %d, want %d", attempts, maxDeleteRetries)
+	}
+}
+
+func TestRetryDeleteHonorsCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	attempts := 0
+	err := retryDelete(ctx, func() error {
+		attempts++
+		return errors.New("should not run")
+	})
+	if err != context.Canceled {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if attempts != 0 {
+		t.Fatalf("attempts = %d, want 0", attempts)
+	}
+}
+
+func TestRetryDeleteStopsWaitingOnCancelDuringBackoff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	attempts := 0
+	start := time.Now()
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	err := retryDelete(ctx, func() error {
+		attempts++
+		return errors.New("transient")
+	})
+	if err != context.Canceled {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed >= deleteRetryBackoff*2 {
+		t.Fatalf("retryDelete took %v, expected cancellation to cut the backoff short", elapsed)
+	}
+}
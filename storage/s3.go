@@ -0,0 +1,346 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+
+	"github.com/peak/s5cmd/objurl"
+)
+
+// metaHeaderPrefix marks a generic metadata key as a user-supplied
+// x-amz-meta-* header, as opposed to one of the reserved header names
+// (ContentType, CacheControl, ContentEncoding, ContentDisposition,
+// StorageClass) applyMetadata/applyUploadMetadata map to first-class S3
+// API fields. The first four match command.monitoredMetadataHeaders.
+const metaHeaderPrefix = "x-amz-meta-"
+
+// maxRetries bounds how many times the SDK retries a single request (one
+// multipart part, one HeadObject, ...) with its built-in exponential
+// backoff before giving up and surfacing the error to the caller.
+const maxRetries = 5
+
+// S3 is the Client implementation for objurl.Remote URLs.
+type S3 struct {
+	bucket string
+	api    *s3.S3
+}
+
+// NewS3 returns an S3 client for the given bucket, using the default AWS
+// credential/region resolution chain.
+func NewS3(bucket string) (*S3, error) {
+	sess, err := session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+		Config:            aws.Config{MaxRetries: aws.Int(maxRetries)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &S3{bucket: bucket, api: s3.New(sess)}, nil
+}
+
+func (s *S3) List(ctx context.Context, url *objurl.ObjectURL, isRecursive bool, mask ListMask) <-chan *Object {
+	ch := make(chan *Object)
+
+	go func() {
+		defer close(ch)
+
+		input := &s3.ListObjectsV2Input{
+			Bucket: aws.String(s.bucket),
+			Prefix: aws.String(url.Path),
+		}
+		if !isRecursive {
+			input.Delimiter = aws.String("/")
+		}
+
+		err := s.api.ListObjectsV2PagesWithContext(ctx, input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+			for _, p := range page.CommonPrefixes {
+				objURL := *url
+				objURL.Path = aws.StringValue(p.Prefix)
+				ch <- &Object{URL: &objURL, Type: ObjectTypeDir}
+			}
+			for _, obj := range page.Contents {
+				objURL := *url
+				objURL.Path = aws.StringValue(obj.Key)
+				ch <- &Object{
+					URL:     &objURL,
+					Type:    ObjectTypeFile,
+					Size:    aws.Int64Value(obj.Size),
+					ModTime: aws.TimeValue(obj.LastModified),
+				}
+			}
+			return true
+		})
+		if err != nil {
+			ch <- &Object{Err: err}
+		}
+	}()
+
+	return ch
+}
+
+func (s *S3) Stat(ctx context.Context, url *objurl.ObjectURL) (*Object, error) {
+	out, err := s.api.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(url.Path),
+	})
+	if isNotFound(err) {
+		return nil, ErrGivenObjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Object{
+		URL:          url,
+		Type:         ObjectTypeFile,
+		Size:         aws.Int64Value(out.ContentLength),
+		ModTime:      aws.TimeValue(out.LastModified),
+		Metadata:     metadataFromHead(out),
+		StorageClass: StorageClass(aws.StringValue(out.StorageClass)),
+	}, nil
+}
+
+// metadataFromHead flattens a HeadObject response into the same shape
+// Put expects: reserved header names as bare keys, user metadata prefixed
+// with x-amz-meta-.
+func metadataFromHead(out *s3.HeadObjectOutput) map[string]string {
+	metadata := make(map[string]string)
+
+	if v := aws.StringValue(out.ContentType); v != "" {
+		metadata["ContentType"] = v
+	}
+	if v := aws.StringValue(out.CacheControl); v != "" {
+		metadata["CacheControl"] = v
+	}
+	if v := aws.StringValue(out.ContentEncoding); v != "" {
+		metadata["ContentEncoding"] = v
+	}
+	if v := aws.StringValue(out.ContentDisposition); v != "" {
+		metadata["ContentDisposition"] = v
+	}
+	for k, v := range out.Metadata {
+		metadata[metaHeaderPrefix+k] = aws.StringValue(v)
+	}
+
+	return metadata
+}
+
+func (s *S3) Copy(ctx context.Context, src, dst *objurl.ObjectURL, metadata map[string]string) error {
+	input := &s3.CopyObjectInput{
+		Bucket:     aws.String(s.bucket),
+		Key:        aws.String(dst.Path),
+		CopySource: aws.String(src.Bucket + "/" + src.Path),
+	}
+
+	if len(metadata) > 0 {
+		input.MetadataDirective = aws.String(s3.MetadataDirectiveReplace)
+		applyMetadata(input, metadata)
+	}
+
+	_, err := s.api.CopyObjectWithContext(ctx, input)
+	return err
+}
+
+// applyMetadata splits metadata into CopyObjectInput's first-class header
+// fields and its generic Metadata map, the same way putObjectInput does
+// for uploads.
+func applyMetadata(input *s3.CopyObjectInput, metadata map[string]string) {
+	userMeta := make(map[string]*string)
+
+	for k, v := range metadata {
+		v := v
+		switch {
+		case k == "ContentType":
+			input.ContentType = aws.String(v)
+		case k == "CacheControl":
+			input.CacheControl = aws.String(v)
+		case k == "ContentEncoding":
+			input.ContentEncoding = aws.String(v)
+		case k == "ContentDisposition":
+			input.ContentDisposition = aws.String(v)
+		case k == "StorageClass":
+			if v != "" {
+				input.StorageClass = aws.String(v)
+			}
+		case strings.HasPrefix(k, metaHeaderPrefix):
+			userMeta[strings.TrimPrefix(k, metaHeaderPrefix)] = aws.String(v)
+		}
+	}
+
+	if len(userMeta) > 0 {
+		input.Metadata = userMeta
+	}
+}
+
+// resolveTuning applies the DefaultXxxChunkSize/DefaultXxxConcurrency
+// fallbacks for a zero-valued PutOptions/GetOptions field, so the s3manager
+// Uploader/Downloader is always configured explicitly rather than falling
+// back to the SDK's own defaults.
+func resolveTuning(chunkSize int64, concurrency int, defaultChunkSize int64, defaultConcurrency int) (int64, int) {
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+	return chunkSize, concurrency
+}
+
+func (s *S3) Get(ctx context.Context, url *objurl.ObjectURL, w io.WriterAt, opts GetOptions) (int64, error) {
+	writer := w
+	if opts.Progress != nil {
+		writer = &progressWriterAt{w: w, progress: opts.Progress}
+	}
+
+	partSize, concurrency := resolveTuning(opts.ChunkSize, opts.Concurrency, DefaultDownloadChunkSize, DefaultDownloadConcurrency)
+	downloader := s3manager.NewDownloaderWithClient(s.api, func(d *s3manager.Downloader) {
+		d.PartSize = partSize
+		d.Concurrency = concurrency
+	})
+
+	// The downloader issues one ranged GetObject request per part
+	// (Range: bytes=N-M) and runs Concurrency of them at a time; each
+	// part is retried independently by the underlying SDK client with
+	// its configured exponential backoff (see NewS3), so one flaky part
+	// doesn't restart the whole object.
+	return downloader.DownloadWithContext(ctx, writer, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(url.Path),
+	})
+}
+
+func (s *S3) Put(ctx context.Context, r io.Reader, url *objurl.ObjectURL, metadata map[string]string, opts PutOptions) error {
+	reader := r
+	if opts.Progress != nil {
+		reader = &progressReader{r: r, progress: opts.Progress}
+	}
+
+	input := &s3manager.UploadInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(url.Path),
+		Body:   reader,
+	}
+	applyUploadMetadata(input, metadata)
+
+	partSize, concurrency := resolveTuning(opts.ChunkSize, opts.Concurrency, DefaultUploadChunkSize, DefaultUploadConcurrency)
+	uploader := s3manager.NewUploaderWithClient(s.api, func(u *s3manager.Uploader) {
+		u.PartSize = partSize
+		u.Concurrency = concurrency
+	})
+
+	// Each part upload is retried independently by the underlying SDK
+	// client (NewS3 configures its exponential backoff). If the upload
+	// still fails, or ctx is cancelled mid-upload (first SIGINT, or
+	// --timeout firing), s3manager.Uploader issues AbortMultipartUpload
+	// itself so no orphaned parts are left billing the bucket.
+	_, err := uploader.UploadWithContext(ctx, input)
+	return err
+}
+
+// applyUploadMetadata splits metadata into UploadInput's first-class
+// header fields and its generic Metadata map.
+func applyUploadMetadata(input *s3manager.UploadInput, metadata map[string]string) {
+	userMeta := make(map[string]*string)
+
+	for k, v := range metadata {
+		v := v
+		switch {
+		case k == "ContentType":
+			input.ContentType = aws.String(v)
+		case k == "CacheControl":
+			input.CacheControl = aws.String(v)
+		case k == "ContentEncoding":
+			input.ContentEncoding = aws.String(v)
+		case k == "ContentDisposition":
+			input.ContentDisposition = aws.String(v)
+		case k == "StorageClass":
+			if v != "" {
+				input.StorageClass = aws.String(v)
+			}
+		case strings.HasPrefix(k, metaHeaderPrefix):
+			userMeta[strings.TrimPrefix(k, metaHeaderPrefix)] = aws.String(v)
+		}
+	}
+
+	if len(userMeta) > 0 {
+		input.Metadata = userMeta
+	}
+}
+
+func (s *S3) Delete(ctx context.Context, url *objurl.ObjectURL) error {
+	return retryDelete(ctx, func() error {
+		_, err := s.api.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(url.Path),
+		})
+		return err
+	})
+}
+
+// Hashes returns the hash types S3 can produce for Hash: "etag" is always
+// available via HeadObject. For non-multipart uploads, the ETag is the
+// hex MD5 of the content, matching Filesystem's "etag".
+func (s *S3) Hashes() []string {
+	return []string{"etag"}
+}
+
+func (s *S3) Hash(ctx context.Context, url *objurl.ObjectURL, hashType string) (string, error) {
+	if hashType != "etag" {
+		return "", nil
+	}
+
+	out, err := s.api.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(url.Path),
+	})
+	if isNotFound(err) {
+		return "", ErrGivenObjectNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Trim(aws.StringValue(out.ETag), `"`), nil
+}
+
+func isNotFound(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	if !ok {
+		return false
+	}
+	return aerr.Code() == s3.ErrCodeNoSuchKey || aerr.Code() == "NotFound"
+}
+
+type progressReader struct {
+	r        io.Reader
+	progress func(int)
+}
+
+func (p *progressReader) Read(buf []byte) (int, error) {
+	n, err := p.r.Read(buf)
+	if n > 0 {
+		p.progress(n)
+	}
+	return n, err
+}
+
+type progressWriterAt struct {
+	w        io.WriterAt
+	progress func(int)
+}
+
+func (p *progressWriterAt) WriteAt(buf []byte, off int64) (int, error) {
+	n, err := p.w.WriteAt(buf, off)
+	if n > 0 {
+		p.progress(n)
+	}
+	return n, err
+}
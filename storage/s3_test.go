@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+func TestResolveTuning(t *testing.T) {
+	tests := []struct {
+		name               string
+		chunkSize          int64
+		concurrency        int
+		defaultChunkSize   int64
+		defaultConcurrency int
+		wantChunkSize      int64
+		wantConcurrency    int
+	}{
+		{"both set", 8 << 20, 10, DefaultUploadChunkSize, DefaultUploadConcurrency, 8 << 20, 10},
+		{"both zero fall back to defaults", 0, 0, DefaultUploadChunkSize, DefaultUploadConcurrency, DefaultUploadChunkSize, DefaultUploadConcurrency},
+		{"negative treated as unset", -1, -1, DefaultDownloadChunkSize, DefaultDownloadConcurrency, DefaultDownloadChunkSize, DefaultDownloadConcurrency},
+		{"only chunk size set", 16 << 20, 0, DefaultDownloadChunkSize, DefaultDownloadConcurrency, 16 << 20, DefaultDownloadConcurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotChunkSize, gotConcurrency := resolveTuning(tt.chunkSize, tt.concurrency, tt.defaultChunkSize, tt.defaultConcurrency)
+			if gotChunkSize != tt.wantChunkSize || gotConcurrency != tt.wantConcurrency {
+				t.Fatalf("resolveTuning(%d, %d, %d, %d) = (%d, %d), want (%d, %d)",
+					tt.chunkSize, tt.concurrency, tt.defaultChunkSize, tt.defaultConcurrency,
+					gotChunkSize, gotConcurrency, tt.wantChunkSize, tt.wantConcurrency)
+			}
+		})
+	}
+}
+
+// TestMetadataFromHeadMatchesMonitoredHeaders pins the reserved header
+// names metadataFromHead writes into Object.Metadata to the names listed in
+// command.monitoredMetadataHeaders (copied here, since storage cannot
+// import command), which --preserve-metadata and --if-metadata-differ
+// compare against. If these ever disagree, Stat
+// silently stops round-tripping a header instead of failing loudly.
+func TestMetadataFromHeadMatchesMonitoredHeaders(t *testing.T) {
+	out := &s3.HeadObjectOutput{
+		ContentType:        aws.String("text/plain"),
+		CacheControl:       aws.String("no-cache"),
+		ContentEncoding:    aws.String("gzip"),
+		ContentDisposition: aws.String("inline"),
+		Metadata:           map[string]*string{"author": aws.String("alice")},
+	}
+
+	got := metadataFromHead(out)
+	want := map[string]string{
+		"ContentType":        "text/plain",
+		"CacheControl":       "no-cache",
+		"ContentEncoding":    "gzip",
+		"ContentDisposition": "inline",
+		metaHeaderPrefix + "author": "alice",
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("metadataFromHead() = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Fatalf("metadataFromHead()[%q] = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+// TestApplyMetadataRoundTripsHeadMetadata checks that every key
+// metadataFromHead produces is understood by applyMetadata/
+// applyUploadMetadata -- i.e. a Stat'd object's Metadata can be fed
+// straight into --preserve-metadata's Copy/Put path.
+func TestApplyMetadataRoundTripsHeadMetadata(t *testing.T) {
+	head := metadataFromHead(&s3.HeadObjectOutput{
+		ContentType:  aws.String("text/plain"),
+		CacheControl: aws.String("no-cache"),
+		Metadata:     map[string]*string{"author": aws.String("alice")},
+	})
+	head["StorageClass"] = "STANDARD_IA"
+
+	copyInput := &s3.CopyObjectInput{}
+	applyMetadata(copyInput, head)
+	if aws.StringValue(copyInput.ContentType) != "text/plain" {
+		t.Fatalf("CopyObjectInput.ContentType = %q, want %q", aws.StringValue(copyInput.ContentType), "text/plain")
+	}
+	if aws.StringValue(copyInput.CacheControl) != "no-cache" {
+		t.Fatalf("CopyObjectInput.CacheControl = %q, want %q", aws.StringValue(copyInput.CacheControl), "no-cache")
+	}
+	if aws.StringValue(copyInput.StorageClass) != "STANDARD_IA" {
+		t.Fatalf("CopyObjectInput.StorageClass = %q, want %q", aws.StringValue(copyInput.StorageClass), "STANDARD_IA")
+	}
+	if got := aws.StringValue(copyInput.Metadata["author"]); got != "alice" {
+		t.Fatalf("CopyObjectInput.Metadata[author] = %q, want %q", got, "alice")
+	}
+
+	uploadInput := &s3manager.UploadInput{}
+	applyUploadMetadata(uploadInput, head)
+	if aws.StringValue(uploadInput.ContentType) != "text/plain" {
+		t.Fatalf("UploadInput.ContentType = %q, want %q", aws.StringValue(uploadInput.ContentType), "text/plain")
+	}
+	if got := aws.StringValue(uploadInput.Metadata["author"]); got != "alice" {
+		t.Fatalf("UploadInput.Metadata[author] = %q, want %q", got, "alice")
+	}
+}
@@ -0,0 +1,158 @@
+// Package storage implements the Client abstraction that every s5cmd
+// command is written against: a uniform List/Stat/Get/Put/Copy/Delete/Hash
+// surface over the local filesystem (Filesystem), S3 (S3) and combine
+// buckets (combine.Client, registered via RegisterCombineFactory).
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/peak/s5cmd/objurl"
+)
+
+// ErrGivenObjectNotFound is returned by Stat and Hash when the requested
+// object does not exist.
+var ErrGivenObjectNotFound = errors.New("given object not found")
+
+// Default multipart tuning, used as the --upload/download-chunk-size and
+// --upload/download-concurrency flag defaults. They mirror the AWS SDK's
+// own s3manager defaults.
+const (
+	DefaultUploadChunkSize     int64 = 5 * 1024 * 1024 // 5MiB, the S3 minimum part size
+	DefaultUploadConcurrency        = 5
+	DefaultDownloadChunkSize   int64 = 5 * 1024 * 1024
+	DefaultDownloadConcurrency      = 5
+)
+
+// ObjectType distinguishes a regular object from a "directory" -- a common
+// prefix for remote listings, or an actual directory on the filesystem.
+type ObjectType int
+
+const (
+	ObjectTypeFile ObjectType = iota
+	ObjectTypeDir
+)
+
+// IsDir reports whether the type represents a directory/prefix.
+func (t ObjectType) IsDir() bool {
+	return t == ObjectTypeDir
+}
+
+// StorageClass is the storage tier an object is stored in.
+type StorageClass string
+
+// LookupClass converts a --storage-class flag value to a StorageClass
+// as given; it does not validate it. An empty value tells Put to omit the
+// field and let the backend apply its own default.
+func LookupClass(s string) StorageClass {
+	return StorageClass(s)
+}
+
+// ListMask controls which entries List returns. Currently there is a
+// single mode: list everything under the prefix.
+type ListMask int
+
+// ListAllItems lists every object (and, when non-recursive, every
+// "directory") under the given prefix.
+const ListAllItems ListMask = 0
+
+// Object describes a single object or "directory" returned by List or
+// Stat.
+type Object struct {
+	URL          *objurl.ObjectURL
+	Type         ObjectType
+	Size         int64
+	ModTime      time.Time
+	Metadata     map[string]string
+	StorageClass StorageClass
+	Err          error
+}
+
+// PutOptions configures Put, including multipart upload tuning and an
+// optional progress callback.
+type PutOptions struct {
+	ChunkSize   int64
+	Concurrency int
+	// Progress, if non-nil, is invoked with the number of bytes written to
+	// the destination as the upload progresses.
+	Progress func(int)
+}
+
+// GetOptions configures Get, including ranged-download tuning and an
+// optional progress callback.
+type GetOptions struct {
+	ChunkSize   int64
+	Concurrency int
+	// Progress, if non-nil, is invoked with the number of bytes written to
+	// the destination as the download progresses.
+	Progress func(int)
+}
+
+// Client is the storage backend interface every command is written
+// against. Use NewClient to obtain the implementation appropriate for a
+// given objurl.ObjectURL.
+type Client interface {
+	// List streams every object under url. If isRecursive is false, only
+	// the immediate children are returned, with ObjectTypeDir entries
+	// standing in for further nesting.
+	List(ctx context.Context, url *objurl.ObjectURL, isRecursive bool, mask ListMask) <-chan *Object
+
+	// Stat returns metadata for a single object. It returns
+	// ErrGivenObjectNotFound if url does not exist.
+	Stat(ctx context.Context, url *objurl.ObjectURL) (*Object, error)
+
+	// Copy copies src to dst within the same backend, applying metadata
+	// (as produced by the command layer's metadata options).
+	Copy(ctx context.Context, src, dst *objurl.ObjectURL, metadata map[string]string) error
+
+	// Get writes the contents of url to w, returning the number of bytes
+	// written.
+	Get(ctx context.Context, url *objurl.ObjectURL, w io.WriterAt, opts GetOptions) (int64, error)
+
+	// Put uploads the contents of r to url with the given metadata.
+	Put(ctx context.Context, r io.Reader, url *objurl.ObjectURL, metadata map[string]string, opts PutOptions) error
+
+	// Delete removes url.
+	Delete(ctx context.Context, url *objurl.ObjectURL) error
+
+	// Hashes returns the content-hash types this backend can produce for
+	// Hash, most-preferred first.
+	Hashes() []string
+
+	// Hash returns the hash of the given type for url. It returns
+	// ErrGivenObjectNotFound if url does not exist.
+	Hash(ctx context.Context, url *objurl.ObjectURL, hashType string) (string, error)
+}
+
+// combineFactory is set by the storage/combine package (via
+// RegisterCombineFactory) to avoid an import cycle between storage and
+// combine, which itself needs to dispatch back into storage.NewClient for
+// each registered upstream.
+var combineFactory func(*objurl.ObjectURL) (Client, error)
+
+// RegisterCombineFactory installs the constructor NewClient uses for
+// objurl.Combine URLs. It is called once, from an init func in the
+// combine package.
+func RegisterCombineFactory(f func(*objurl.ObjectURL) (Client, error)) {
+	combineFactory = f
+}
+
+// NewClient returns the Client implementation appropriate for url's type.
+func NewClient(url *objurl.ObjectURL) (Client, error) {
+	switch url.Type {
+	case objurl.Local:
+		return NewFilesystem(), nil
+	case objurl.Remote:
+		return NewS3(url.Bucket)
+	case objurl.Combine:
+		if combineFactory == nil {
+			return nil, errors.New("combine buckets are not available: storage/combine was not imported")
+		}
+		return combineFactory(url)
+	default:
+		return nil, errors.New("unknown URL type")
+	}
+}